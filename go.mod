@@ -3,9 +3,12 @@ module github.com/NOTAschool/gqmd
 go 1.25.5
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/mark3labs/mcp-go v0.43.2
 	github.com/ncruces/go-sqlite3 v0.30.5
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/net v0.49.0
+	golang.org/x/text v0.33.0
 )
 
 require (
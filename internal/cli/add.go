@@ -34,8 +34,14 @@ var addCmd = &cobra.Command{
 		}
 
 		pattern, _ := cmd.Flags().GetString("pattern")
+		var maxSize int64
+		if v, _ := cmd.Flags().GetString("max-size"); v != "" {
+			if maxSize, err = store.ParseMaxFileSize(v); err != nil {
+				return err
+			}
+		}
 
-		db, err := store.Open()
+		db, err := openStore()
 		if err != nil {
 			return err
 		}
@@ -44,6 +50,11 @@ var addCmd = &cobra.Command{
 		if err := db.AddCollection(name, absPath, pattern); err != nil {
 			return fmt.Errorf("failed to add collection: %w", err)
 		}
+		if maxSize != 0 {
+			if err := db.SetMaxFileSize(name, maxSize); err != nil {
+				return err
+			}
+		}
 
 		fmt.Printf("Added collection %q -> %s\n", name, absPath)
 		return nil
@@ -51,6 +62,7 @@ var addCmd = &cobra.Command{
 }
 
 func init() {
-	addCmd.Flags().StringP("pattern", "p", "**/*.md", "Glob pattern for files")
+	addCmd.Flags().StringP("pattern", "p", "**/*.md", "Glob pattern for files, e.g. \"**/*.{md,org,txt}\" for several formats")
+	addCmd.Flags().String("max-size", "", "Skip files larger than this, e.g. 50MB, or none for no limit")
 	rootCmd.AddCommand(addCmd)
 }
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the index to a portable archive",
+	Long: `Write collections, documents, content, contexts, chunks and embeddings to a
+versioned, gzipped archive. Use - for stdout.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collections, _ := cmd.Flags().GetStringSlice("collection")
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var w io.Writer = os.Stdout
+		var f *os.File
+		if args[0] != "-" {
+			if f, err = os.Create(args[0]); err != nil {
+				return err
+			}
+			w = f
+		}
+
+		stats, err := db.Export(w, store.ExportOptions{Collections: collections})
+		if f != nil {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(args[0])
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %s\n", archiveSummary(stats))
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import an archive written by export",
+	Long: `Load an archive written by gqmd export. Collection roots can be moved with
+--remap, either by name (notes=/srv/notes) or by path prefix
+(/home/alice=/home/bob). Only embeddings from the configured model are
+imported; others are skipped with a warning. Use --skip-embeddings to import
+without any and re-embed. Use - for stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remapFlags, _ := cmd.Flags().GetStringArray("remap")
+		remap := make(map[string]string, len(remapFlags))
+		for _, r := range remapFlags {
+			from, to, ok := strings.Cut(r, "=")
+			if !ok || from == "" || to == "" {
+				return fmt.Errorf("invalid --remap %q, want old=new", r)
+			}
+			remap[from] = to
+		}
+		opts := store.ImportOptions{Remap: remap}
+		opts.Replace, _ = cmd.Flags().GetBool("replace")
+		opts.SkipEmbeddings, _ = cmd.Flags().GetBool("skip-embeddings")
+		model, _ := cmd.Flags().GetString("model")
+		opts.Model = embed.NewClientFromEnv(model).Model()
+
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stats, err := db.Import(r, opts)
+		if err != nil {
+			return err
+		}
+		for _, m := range stats.SkippedModels {
+			fmt.Fprintf(os.Stderr, "Warning: skipped embeddings from %s (%d dimensions), run gqmd embed to embed with %s\n", m.Model, m.Dimensions, opts.Model)
+		}
+		fmt.Printf("Imported %s\n", archiveSummary(stats))
+		return nil
+	},
+}
+
+func archiveSummary(s *store.ArchiveStats) string {
+	return fmt.Sprintf("%d collections, %d documents, %d contexts, %d embeddings (archive v%d)",
+		s.Collections, s.Documents, s.Contexts, s.Embeddings, s.Version)
+}
+
+func init() {
+	exportCmd.Flags().StringSliceP("collection", "c", nil, "Export only these collections")
+	importCmd.Flags().StringArray("remap", nil, "Move a collection root: name=path or old-prefix=new-prefix (repeatable)")
+	importCmd.Flags().Bool("replace", false, "Replace existing collections with the same name")
+	importCmd.Flags().Bool("skip-embeddings", false, "Import without embeddings and chunks")
+	importCmd.Flags().StringP("model", "m", "", "Embedding model in use (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
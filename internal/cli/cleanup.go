@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove orphaned data and compact the index",
+	Long: `Remove content, full-text entries, embeddings and chunks no document refers
+to any more, then optimize the full-text index and VACUUM the database.
+Scan and remove already drop orphans; cleanup also returns the space to disk.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noVacuum, _ := cmd.Flags().GetBool("no-vacuum")
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := db.Cleanup(store.CleanupOptions{Vacuum: !noVacuum})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed: %d content, %d FTS rows, %d embeddings, %d chunks\n",
+			result.Content, result.FTSRows, result.Embeddings, result.Chunks)
+		fmt.Printf("Reclaimed: %s (%s -> %s)\n", store.FormatBytes(result.Reclaimed()),
+			store.FormatBytes(result.BytesBefore), store.FormatBytes(result.BytesAfter))
+		return nil
+	},
+}
+
+func init() {
+	cleanupCmd.Flags().Bool("no-vacuum", false, "Only remove orphaned rows, skip VACUUM and FTS optimize")
+	rootCmd.AddCommand(cleanupCmd)
+}
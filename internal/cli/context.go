@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage path contexts",
+	Long: `Attach human descriptions to collections, folders or files.
+A context is returned with every result under its path, including subfolders.`,
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <collection[/path]> <text>",
+	Short: "Add or replace a path context",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, path, _ := strings.Cut(args[0], "/")
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if err := db.AddContext(collection, path, args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added context for %s\n", args[0])
+		return nil
+	},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List path contexts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		contexts, err := db.ListContexts()
+		if err != nil {
+			return err
+		}
+
+		if len(contexts) == 0 {
+			fmt.Println("No contexts")
+			return nil
+		}
+
+		for _, c := range contexts {
+			ref := c.Collection
+			if c.Path != "" {
+				ref += "/" + c.Path
+			}
+			fmt.Printf("%s: %s\n", ref, c.Context)
+		}
+		return nil
+	},
+}
+
+var contextRemoveCmd = &cobra.Command{
+	Use:   "rm <collection[/path]>",
+	Short: "Remove a path context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, path, _ := strings.Cut(args[0], "/")
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if err := db.RemoveContext(collection, path); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed context for %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextAddCmd, contextListCmd, contextRemoveCmd)
+	rootCmd.AddCommand(contextCmd)
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// pingTimeout bounds the Ollama reachability check
+const pingTimeout = 3 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the index for problems",
+	Long: `Check SQLite integrity, that the full-text index matches the documents,
+that no data is orphaned, that collection directories still exist, that each
+embedding model uses a single dimension, and that Ollama is reachable.
+Embeddings kept for other models are listed but left alone.
+With --fix, problems that can be repaired automatically are repaired.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+		model, _ := cmd.Flags().GetString("model")
+		client := embed.NewClientFromEnv(model)
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		findings, err := db.Diagnose(store.DiagnoseOptions{Model: client.Model()})
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		defer cancel()
+		ollama := store.Finding{Check: "ollama"}
+		if err := client.Ping(ctx); err != nil {
+			ollama.Problem = err.Error()
+			ollama.Fix = fmt.Sprintf("start Ollama (ollama serve), set OLLAMA_HOST, or run ollama pull %s", client.Model())
+		}
+		findings = append(findings, ollama)
+
+		problems := 0
+		for _, f := range findings {
+			if f.OK() {
+				fmt.Printf("[ok]    %s\n", f.Check)
+				if f.Note != "" {
+					fmt.Printf("        %s\n", f.Note)
+				}
+				continue
+			}
+			if fix && f.Fixable() {
+				if err := f.Apply(); err != nil {
+					fmt.Printf("[fail]  %s: %s\n        fix failed: %v\n", f.Check, f.Problem, err)
+					problems++
+					continue
+				}
+				fmt.Printf("[fixed] %s: %s\n", f.Check, f.Problem)
+				continue
+			}
+			problems++
+			fmt.Printf("[fail]  %s: %s\n        fix: %s", f.Check, f.Problem, f.Fix)
+			if f.Fixable() {
+				fmt.Print(" (gqmd doctor --fix)")
+			}
+			fmt.Println()
+		}
+
+		if problems > 0 {
+			cmd.SilenceUsage = true
+			if problems == 1 {
+				return fmt.Errorf("1 problem found")
+			}
+			return fmt.Errorf("%d problems found", problems)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().Bool("fix", false, "Repair problems that can be fixed automatically")
+	doctorCmd.Flags().StringP("model", "m", "", "Embedding model (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed [name]",
+	Short: "Generate vector embeddings",
+	Long:  `Generate embeddings via Ollama for documents that have none for the current model.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var collection string
+		if len(args) > 0 {
+			collection = args[0]
+		}
+		model, _ := cmd.Flags().GetString("model")
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		client := embed.NewClientFromEnv(model)
+
+		bar := newProgressBar("embed")
+		result, err := db.EmbedDocuments(client.Model(), client.Embed, store.EmbedOptions{
+			Collection: collection,
+			Progress:   bar.Func(),
+		})
+		bar.Done()
+		if err != nil {
+			return fmt.Errorf("embedding failed: %w", err)
+		}
+
+		fmt.Printf("Embedded: %d, Errors: %d\n", result.Embedded, result.Errors)
+		return nil
+	},
+}
+
+func init() {
+	embedCmd.Flags().StringP("model", "m", "", "Embedding model (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+	rootCmd.AddCommand(embedCmd)
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// outputFormat selects how a command prints its results
+type outputFormat string
+
+const (
+	formatText  outputFormat = "text"
+	formatJSON  outputFormat = "json"
+	formatCSV   outputFormat = "csv"
+	formatMD    outputFormat = "md"
+	formatFiles outputFormat = "files"
+	formatXML   outputFormat = "xml"
+)
+
+func parseFormat(s string) (outputFormat, error) {
+	switch f := outputFormat(s); f {
+	case formatText, formatJSON, formatCSV, formatMD, formatFiles, formatXML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, want text, json, csv, md, files or xml", s)
+	}
+}
+
+// addFormatFlag registers the shared --format flag on a command
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("format", "f", "text", "Output format: text, json, csv, md, files or xml")
+}
+
+func getFormat(cmd *cobra.Command) (outputFormat, error) {
+	s, _ := cmd.Flags().GetString("format")
+	return parseFormat(s)
+}
+
+// table is a format-neutral view of command output. Each row holds one
+// value per column.
+type table struct {
+	name    string // XML element name of a row
+	columns []string
+	rows    [][]any
+	pathCol int  // column printed by --format files, -1 if none
+	single  bool // the table describes one object, not a list
+}
+
+// render writes the table in a machine-readable format. Text output is
+// left to each command.
+func render(w io.Writer, f outputFormat, t *table) error {
+	switch f {
+	case formatJSON:
+		return renderJSON(w, t)
+	case formatCSV:
+		return renderCSV(w, t)
+	case formatMD:
+		return renderMD(w, t)
+	case formatFiles:
+		return renderFiles(w, t)
+	case formatXML:
+		return renderXML(w, t)
+	default:
+		return fmt.Errorf("format %q is not a table format", f)
+	}
+}
+
+// renderJSON writes an array of objects, or a single object, keeping column order
+func renderJSON(w io.Writer, t *table) error {
+	var b bytes.Buffer
+	if !t.single {
+		b.WriteString("[")
+	}
+	for i, row := range t.rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if !t.single {
+			b.WriteString("\n  ")
+		}
+		b.WriteString("{")
+		for j, col := range t.columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := marshalJSON(col)
+			val, err := marshalJSON(row[j])
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteString(": ")
+			b.Write(val)
+		}
+		b.WriteString("}")
+	}
+	if !t.single {
+		if len(t.rows) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("]")
+	}
+	b.WriteString("\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeJSON writes v as indented JSON, for output that does not fit a table
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// marshalJSON encodes v without escaping HTML characters, which snippets use
+func marshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func renderCSV(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.columns); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		if err := cw.Write(formatRow(row)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderMD(w io.Writer, t *table) error {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(t.columns, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(t.columns)) + "\n")
+	for _, row := range t.rows {
+		cells := formatRow(row)
+		for i, c := range cells {
+			c = strings.ReplaceAll(c, "|", `\|`)
+			cells[i] = strings.ReplaceAll(c, "\n", "<br>")
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderFiles(w io.Writer, t *table) error {
+	if t.pathCol < 0 {
+		return fmt.Errorf("format files is not supported for this command")
+	}
+	for _, row := range t.rows {
+		if _, err := fmt.Fprintln(w, row[t.pathCol]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderXML(w io.Writer, t *table) error {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<results>\n")
+	for _, row := range t.rows {
+		b.WriteString("  <" + t.name + ">\n")
+		for j, col := range t.columns {
+			b.WriteString("    <" + col + ">")
+			if err := xml.EscapeText(&b, []byte(formatValue(row[j]))); err != nil {
+				return err
+			}
+			b.WriteString("</" + col + ">\n")
+		}
+		b.WriteString("  </" + t.name + ">\n")
+	}
+	b.WriteString("</results>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func formatRow(row []any) []string {
+	cells := make([]string, len(row))
+	for i, v := range row {
+		cells[i] = formatValue(v)
+	}
+	return cells
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return fmt.Sprintf("%.4f", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRenderGolden(t *testing.T) {
+	results := []store.SearchResult{
+		{Collection: "notes", Path: "infra/standup.md", Title: "Standup", DocID: "#abc123",
+			Context: "Infra team", Snippet: "the <mark>kubernetes</mark> upgrade", Score: 0.5556,
+			Explanation: &store.Explanation{Rank: 1, BM25: -1.25, TitleBM25: -0.5, BodyBM25: -1}},
+		{Collection: "notes", Path: "a|b.md", Title: "Pipes, \"quotes\" & <tags>", DocID: "#def456",
+			Snippet: "line one\nline two", Score: 0.3333,
+			Explanation: &store.Explanation{Rank: 2, BM25: -0.5, BodyBM25: -0.5}},
+	}
+	status := testStatus()
+
+	tables := map[string]*table{
+		"search": searchTable(results),
+		"status": statusTable(status),
+	}
+	formats := []outputFormat{formatJSON, formatCSV, formatMD, formatFiles, formatXML}
+
+	for name, tbl := range tables {
+		for _, f := range formats {
+			if f == formatFiles && tbl.pathCol < 0 {
+				continue
+			}
+			t.Run(name+"."+string(f), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := render(&buf, f, tbl); err != nil {
+					t.Fatalf("render failed: %v", err)
+				}
+				checkGolden(t, filepath.Join("testdata", name+"."+string(f)+".golden"), buf.Bytes())
+			})
+		}
+	}
+}
+
+func testStatus() *store.Status {
+	return &store.Status{
+		DBPath: "/tmp/index.sqlite", SchemaVersion: 4, TotalDocs: 2, Collections: 1, HasVectorIndex: true,
+		ContentBytes: 2048, DBBytes: 65536, FTSBytes: 4096, Model: "nomic-embed-text", NeedsEmbedding: 1,
+		Oldest: &store.DocumentAge{Collection: "notes", Path: "a.md", ModifiedAt: "2024-01-01T00:00:00Z"},
+		Newest: &store.DocumentAge{Collection: "notes", Path: "b.md", ModifiedAt: "2024-02-01T00:00:00Z"},
+		CollectionStats: []store.CollectionStatus{{Name: "notes", Path: "/home/me/notes", Docs: 2, ContentBytes: 2048,
+			NeedsEmbedding: 1, LastScanAt: "2024-02-01T00:00:00Z", LastScanMillis: 1500}},
+		Embeddings: []store.ModelCoverage{{Model: "nomic-embed-text", Dimensions: 768, Docs: 1, Chunks: 3, Coverage: 0.5}},
+	}
+}
+
+func TestStatusOutputGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, testStatus()); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join("testdata", "status.full.json.golden"), buf.Bytes())
+	checkGolden(t, filepath.Join("testdata", "status.txt.golden"), []byte(testStatus().String()+"\n"))
+}
+
+func TestScanOutputGolden(t *testing.T) {
+	results := []*store.ScanResult{
+		{Collection: "notes", Added: 2, Updated: 1, Unchanged: 40, Removed: 3, DurationMillis: 1250,
+			Skipped: []store.SkippedFile{{Path: "scan.png.md", Reason: "binary"}},
+			Errors: []store.ScanError{
+				{Path: "private/keys.md", Stage: store.StageRead, Err: errors.New("permission denied")},
+				{Path: "broken.ipynb", Stage: store.StageExtract, Err: errors.New("unexpected end of JSON input")},
+			}},
+		{Collection: "gone", Skipped: []store.SkippedFile{},
+			Errors: []store.ScanError{{Stage: store.StageScan, Err: errors.New("no such directory")}}},
+	}
+
+	for name, verbose := range map[string]bool{"scan.txt": false, "scan.verbose.txt": true} {
+		var buf bytes.Buffer
+		for _, r := range results {
+			writeScanResult(&buf, r, verbose)
+		}
+		checkGolden(t, filepath.Join("testdata", name+".golden"), buf.Bytes())
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join("testdata", "scan.json.golden"), buf.Bytes())
+}
+
+func TestRenderFilesUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := render(&buf, formatFiles, statusTable(&store.Status{})); err == nil {
+		t.Error("expected error rendering status as files")
+	}
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v (run go test -update)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output mismatch for %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
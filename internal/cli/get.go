@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get <collection/path[:line]|#docid>",
+	Short: "Get a document",
+	Long:  `Print a document, or a line range or heading section of it.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts store.SliceOptions
+		opts.MaxLines, _ = cmd.Flags().GetInt("max-lines")
+		opts.Section, _ = cmd.Flags().GetString("section")
+		lineNumbers, _ := cmd.Flags().GetBool("line-numbers")
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		doc, content, line, err := store.GetWithLine(args[0], db.GetByRef)
+		if err != nil {
+			return fmt.Errorf("document not found: %w", err)
+		}
+		opts.FromLine = line
+		if cmd.Flags().Changed("from") {
+			opts.FromLine, _ = cmd.Flags().GetInt("from")
+		}
+
+		sl, err := store.SliceContent(content, opts)
+		if err != nil {
+			return err
+		}
+
+		if format != formatText {
+			return render(os.Stdout, format, documentTable(doc, sl, lineNumbers))
+		}
+
+		if lineNumbers {
+			fmt.Println(sl.Numbered())
+		} else {
+			fmt.Println(sl.Content)
+		}
+		return nil
+	},
+}
+
+func documentTable(doc *store.Document, sl store.Slice, lineNumbers bool) *table {
+	content := sl.Content
+	if lineNumbers {
+		content = sl.Numbered()
+	}
+	return &table{
+		name:    "document",
+		columns: []string{"path", "docid", "title", "context", "modified_at", "from_line", "to_line", "total_lines", "content"},
+		rows: [][]any{{doc.Collection + "/" + doc.Path, doc.DocID, doc.Title, doc.Context, doc.ModifiedAt,
+			sl.FromLine, sl.ToLine, sl.TotalLines, content}},
+		pathCol: 0,
+		single:  true,
+	}
+}
+
+func init() {
+	getCmd.Flags().Int("from", 0, "First line to print (1-based)")
+	getCmd.Flags().IntP("max-lines", "l", 0, "Max lines to print")
+	getCmd.Flags().StringP("section", "s", "", "Print only the content under this heading")
+	getCmd.Flags().Bool("line-numbers", false, "Prefix each line with its line number")
+	addFormatFlag(getCmd)
+	rootCmd.AddCommand(getCmd)
+}
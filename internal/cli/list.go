@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
@@ -12,7 +13,12 @@ var listCmd = &cobra.Command{
 	Short: "List collections",
 	Long:  `List all registered collections.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		db, err := store.Open()
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		db, err := openStore()
 		if err != nil {
 			return err
 		}
@@ -23,6 +29,10 @@ var listCmd = &cobra.Command{
 			return err
 		}
 
+		if format != formatText {
+			return render(os.Stdout, format, collectionsTable(cols))
+		}
+
 		if len(cols) == 0 {
 			fmt.Println("No collections")
 			return nil
@@ -35,6 +45,19 @@ var listCmd = &cobra.Command{
 	},
 }
 
+func collectionsTable(cols []store.Collection) *table {
+	t := &table{
+		name:    "collection",
+		columns: []string{"name", "path", "pattern", "created_at"},
+		pathCol: 1,
+	}
+	for _, c := range cols {
+		t.rows = append(t.rows, []any{c.Name, c.Path, c.Pattern, c.CreatedAt})
+	}
+	return t
+}
+
 func init() {
+	addFormatFlag(listCmd)
 	rootCmd.AddCommand(listCmd)
 }
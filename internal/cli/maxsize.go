@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var maxSizeCmd = &cobra.Command{
+	Use:   "max-size <name> [size]",
+	Short: "Show or set a collection's file size limit",
+	Long: `Show or set the largest file scans of a collection index, e.g. 50MB.
+Larger files are skipped and reported by scan. 0 restores the default of
+` + store.FormatBytes(store.DefaultMaxFileSize) + ` and "none" removes the limit.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if len(args) == 2 {
+			size, err := store.ParseMaxFileSize(args[1])
+			if err != nil {
+				return err
+			}
+			if err := db.SetMaxFileSize(name, size); err != nil {
+				return err
+			}
+		}
+
+		col, err := db.GetCollection(name)
+		if err != nil {
+			return fmt.Errorf("collection %q not found", name)
+		}
+		fmt.Printf("%s: %s\n", name, describeMaxFileSize(col.MaxFileSize))
+		return nil
+	},
+}
+
+func describeMaxFileSize(size int64) string {
+	switch {
+	case size == 0:
+		return store.FormatBytes(store.DefaultMaxFileSize) + " (default)"
+	case size < 0:
+		return "no limit"
+	}
+	return store.FormatBytes(size)
+}
+
+func init() {
+	rootCmd.AddCommand(maxSizeCmd)
+}
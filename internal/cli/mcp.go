@@ -10,6 +10,22 @@ var mcpCmd = &cobra.Command{
 	Short: "Start MCP server (stdio transport)",
 	Long:  `Start the Model Context Protocol server for AI agent integration.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return mcp.StartServer()
+		var opts mcp.Options
+		var err error
+		if opts.DBPath, err = resolveDBPath(); err != nil {
+			return err
+		}
+		opts.AllowWrite, _ = cmd.Flags().GetBool("allow-write")
+		if withWatch, _ := cmd.Flags().GetBool("watch"); withWatch {
+			w := watchOptions(cmd)
+			opts.Watch = &w
+		}
+		return mcp.StartServer(opts)
 	},
 }
+
+func init() {
+	mcpCmd.Flags().Bool("allow-write", false, "Expose tools that add, remove and scan collections")
+	mcpCmd.Flags().Bool("watch", false, "Reindex files as they change while the server runs")
+	addWatchFlags(mcpCmd)
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var multiGetCmd = &cobra.Command{
+	Use:   "multi-get <paths...>",
+	Short: "Get multiple documents",
+	Long: `Print multiple documents by collection/path, #docid or glob pattern
+such as "notes/2026/**/*.md". A single argument may be a comma-separated
+list; write a comma inside a path as "\,".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxBytes, _ := cmd.Flags().GetInt("max-bytes")
+		maxLines, _ := cmd.Flags().GetInt("max-lines")
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		refs := args
+		if len(args) == 1 {
+			refs = store.SplitRefs(args[0])
+		}
+		resp, err := db.MultiGet(refs, maxBytes, maxLines)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Missing: %s\n", strings.Join(resp.Missing, ", "))
+		}
+		if len(resp.Skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipped (max bytes reached): %s\n", strings.Join(resp.Skipped, ", "))
+		}
+
+		if format != formatText {
+			return render(os.Stdout, format, multiGetTable(resp.Results))
+		}
+
+		if len(resp.Results) == 0 {
+			fmt.Println("No documents found")
+			return nil
+		}
+
+		for _, r := range resp.Results {
+			fmt.Printf("## %s/%s %s\n\n%s\n\n", r.Document.Collection, r.Document.Path, r.Document.DocID, r.Content)
+		}
+		return nil
+	},
+}
+
+func multiGetTable(results []store.MultiGetResult) *table {
+	t := &table{
+		name:    "document",
+		columns: []string{"path", "docid", "title", "context", "truncated", "content"},
+		pathCol: 0,
+	}
+	for _, r := range results {
+		d := r.Document
+		t.rows = append(t.rows, []any{d.Collection + "/" + d.Path, d.DocID, d.Title, d.Context, r.Truncated, r.Content})
+	}
+	return t
+}
+
+func init() {
+	multiGetCmd.Flags().Int("max-bytes", 10*1024, "Max total bytes")
+	multiGetCmd.Flags().IntP("max-lines", "l", 0, "Max lines per document")
+	addFormatFlag(multiGetCmd)
+	rootCmd.AddCommand(multiGetCmd)
+}
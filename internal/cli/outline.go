@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var outlineCmd = &cobra.Command{
+	Use:   "outline <collection/path|#docid>",
+	Short: "Show a document outline",
+	Long:  `Show the heading tree of a document with line numbers and section sizes.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		_, content, err := db.GetByRef(args[0])
+		if err != nil {
+			return fmt.Errorf("document not found: %w", err)
+		}
+
+		entries := store.BuildOutline(content)
+		if format != formatText {
+			return render(os.Stdout, format, outlineTable(entries))
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No headings found")
+			return nil
+		}
+
+		for _, e := range entries {
+			fmt.Printf("%s%s %s (lines %d-%d, %d bytes)\n",
+				strings.Repeat("  ", e.Level-1), strings.Repeat("#", e.Level), e.Text, e.Line, e.EndLine, e.Bytes)
+		}
+		return nil
+	},
+}
+
+func outlineTable(entries []store.OutlineEntry) *table {
+	t := &table{
+		name:    "heading",
+		columns: []string{"level", "text", "line", "end_line", "bytes"},
+		pathCol: -1,
+	}
+	for _, e := range entries {
+		t.rows = append(t.rows, []any{e.Level, e.Text, e.Line, e.EndLine, e.Bytes})
+	}
+	return t
+}
+
+func init() {
+	addFormatFlag(outlineCmd)
+	rootCmd.AddCommand(outlineCmd)
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders store progress as a single-line bar on stderr.
+// It stays silent when stderr is not a terminal, e.g. under systemd.
+type progressBar struct {
+	label string
+	last  time.Time
+	drawn bool
+}
+
+func newProgressBar(label string) *progressBar {
+	return &progressBar{label: label}
+}
+
+// Func returns the store callback driving the bar, or nil when stderr is not a terminal
+func (p *progressBar) Func() store.ProgressFunc {
+	if !isTerminal(os.Stderr) {
+		return nil
+	}
+	return p.update
+}
+
+func (p *progressBar) update(done, total int, message string) {
+	if done < total && time.Since(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = time.Now()
+	p.drawn = true
+
+	filled := 0
+	if total > 0 {
+		filled = done * progressBarWidth / total
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	if len(message) > 40 {
+		message = "..." + message[len(message)-37:]
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s [%s] %d/%d %s", p.label, bar, done, total, message)
+}
+
+// Done clears the bar line
+func (p *progressBar) Done() {
+	if p.drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
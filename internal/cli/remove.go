@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 
-	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -15,7 +14,7 @@ var removeCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
-		db, err := store.Open()
+		db, err := openStore()
 		if err != nil {
 			return err
 		}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 
+	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +20,26 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// resolveDBPath returns the database chosen by --db, --index or $GQMD_DB
+func resolveDBPath() (string, error) {
+	dbPath, _ := rootCmd.PersistentFlags().GetString("db")
+	index, _ := rootCmd.PersistentFlags().GetString("index")
+	return store.ResolvePath(dbPath, index)
+}
+
+// openStore opens the database chosen by --db, --index or $GQMD_DB
+func openStore() (*store.Store, error) {
+	dbPath, err := resolveDBPath()
+	if err != nil {
+		return nil, err
+	}
+	return store.OpenPath(dbPath)
+}
+
 func init() {
+	rootCmd.PersistentFlags().String("db", "", "Index database file (default $GQMD_DB, else the default index)")
+	rootCmd.PersistentFlags().String("index", "", "Use the named index $XDG_CACHE_HOME/gqmd/<name>.sqlite")
+
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(mcpCmd)
 	rootCmd.AddCommand(statusCmd)
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
@@ -10,45 +12,102 @@ import (
 var scanCmd = &cobra.Command{
 	Use:   "scan [name]",
 	Short: "Scan and index a collection",
-	Long:  `Scan a collection directory and index all matching documents.`,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Scan a collection directory, or every collection, and index all matching
+documents. Each collection reports files added, updated, unchanged, removed and
+skipped, and every file that failed with the stage it failed at. --verbose
+also lists skipped files; --json prints the full results.
+
+Files are read and hashed by --workers goroutines and written in transactions
+of --batch-size documents.
+
+Exits with a non-zero status if any file or collection failed.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		db, err := store.Open()
+		workers, _ := cmd.Flags().GetInt("workers")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		opts := store.ScanOptions{Workers: workers, BatchSize: batchSize}
+
+		db, err := openStore()
 		if err != nil {
 			return err
 		}
 		defer db.Close()
 
-		if len(args) == 0 {
-			// Scan all collections
+		names := args
+		if len(names) == 0 {
 			cols, err := db.ListCollections()
 			if err != nil {
 				return err
 			}
 			for _, col := range cols {
-				fmt.Printf("Scanning %s...\n", col.Name)
-				result, err := db.ScanCollection(col.Name)
-				if err != nil {
-					fmt.Printf("  Error: %v\n", err)
-					continue
+				names = append(names, col.Name)
+			}
+		}
+
+		results := []*store.ScanResult{}
+		failed := 0
+		for _, name := range names {
+			if !asJSON {
+				fmt.Printf("Scanning %s...\n", name)
+			}
+			result, err := scanWithProgress(db, name, opts)
+			if err != nil {
+				// Keep what the scan got through and record why it stopped
+				if result == nil {
+					result = &store.ScanResult{Collection: name, Skipped: []store.SkippedFile{}}
 				}
-				fmt.Printf("  Added: %d\n", result.Added)
+				result.Errors = append(result.Errors, store.ScanError{Stage: store.StageScan, Err: err})
+			}
+			failed += len(result.Errors)
+			results = append(results, result)
+			if !asJSON {
+				writeScanResult(os.Stdout, result, verbose)
 			}
-			return nil
 		}
 
-		// Scan specific collection
-		name := args[0]
-		fmt.Printf("Scanning %s...\n", name)
-		result, err := db.ScanCollection(name)
-		if err != nil {
-			return err
+		if asJSON {
+			if err := writeJSON(os.Stdout, results); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			if failed == 1 {
+				return fmt.Errorf("1 error")
+			}
+			return fmt.Errorf("%d errors", failed)
 		}
-		fmt.Printf("Added: %d, Errors: %d\n", result.Added, result.Errors)
 		return nil
 	},
 }
 
+func scanWithProgress(db *store.Store, name string, opts store.ScanOptions) (*store.ScanResult, error) {
+	bar := newProgressBar(name)
+	defer bar.Done()
+	opts.Progress = bar.Func()
+	return db.ScanCollectionWithOptions(name, opts)
+}
+
+// writeScanResult prints a collection's summary and its errors, and with
+// verbose its skipped files
+func writeScanResult(w io.Writer, result *store.ScanResult, verbose bool) {
+	fmt.Fprintf(w, "  %s\n", result)
+	for _, e := range result.Errors {
+		fmt.Fprintf(w, "  error: %s\n", &e)
+	}
+	if verbose {
+		for _, f := range result.Skipped {
+			fmt.Fprintf(w, "  skipped: %s: %s\n", f.Path, f.Reason)
+		}
+	}
+}
+
 func init() {
+	scanCmd.Flags().Int("workers", 0, "Goroutines walking, reading and hashing files (default: number of CPUs)")
+	scanCmd.Flags().Int("batch-size", store.DefaultScanBatch, "Documents written per transaction")
+	scanCmd.Flags().BoolP("verbose", "v", false, "Also list skipped files")
+	scanCmd.Flags().Bool("json", false, "Print results as JSON")
 	rootCmd.AddCommand(scanCmd)
 }
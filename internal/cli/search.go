@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
@@ -14,33 +15,82 @@ var searchCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
-		limit, _ := cmd.Flags().GetInt("limit")
+		var opts store.SearchOptions
+		opts.Limit, _ = cmd.Flags().GetInt("limit")
+		opts.Offset, _ = cmd.Flags().GetInt("offset")
+		opts.Cursor, _ = cmd.Flags().GetString("cursor")
+		opts.MinScore, _ = cmd.Flags().GetFloat64("min-score")
+		opts.Explain, _ = cmd.Flags().GetBool("explain")
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
 
-		db, err := store.Open()
+		db, err := openStore()
 		if err != nil {
 			return err
 		}
 		defer db.Close()
 
-		results, err := db.Search(query, limit)
+		results, page, err := db.SearchWithOptions(query, opts)
 		if err != nil {
 			return err
 		}
 
+		if page.HasMore {
+			fmt.Fprintf(os.Stderr, "More results: --cursor %s\n", page.NextCursor)
+		}
+
+		if format != formatText {
+			return render(os.Stdout, format, searchTable(results))
+		}
+
 		if len(results) == 0 {
 			fmt.Println("No results found")
 			return nil
 		}
 
 		for i, r := range results {
-			fmt.Printf("%d. %s/%s\n", i+1, r.Collection, r.Path)
-			fmt.Printf("   %s\n\n", r.Title)
+			fmt.Printf("%d. %s/%s %s (%.3f)\n", i+1, r.Collection, r.Path, r.DocID, r.Score)
+			fmt.Printf("   %s\n", r.Title)
+			if r.Context != "" {
+				fmt.Printf("   Context: %s\n", r.Context)
+			}
+			if r.Explanation != nil {
+				fmt.Printf("   Explain: %s\n", r.Explanation)
+			}
+			fmt.Printf("   %s\n\n", r.Snippet)
 		}
 		return nil
 	},
 }
 
+func searchTable(results []store.SearchResult) *table {
+	t := &table{
+		name:    "result",
+		columns: []string{"path", "docid", "title", "score", "context", "snippet"},
+		pathCol: 0,
+	}
+	explain := len(results) > 0 && results[0].Explanation != nil
+	if explain {
+		t.columns = append(t.columns, "rank", "bm25", "bm25_filepath", "bm25_title", "bm25_body")
+	}
+	for _, r := range results {
+		row := []any{r.Collection + "/" + r.Path, r.DocID, r.Title, r.Score, r.Context, r.Snippet}
+		if ex := r.Explanation; explain {
+			row = append(row, ex.Rank, ex.BM25, ex.FilepathBM25, ex.TitleBM25, ex.BodyBM25)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
 func init() {
 	searchCmd.Flags().IntP("limit", "n", 10, "Max results")
+	searchCmd.Flags().Int("offset", 0, "Number of results to skip")
+	searchCmd.Flags().String("cursor", "", "Cursor from a previous search to fetch the next page")
+	searchCmd.Flags().Float64("min-score", 0, "Minimum normalized score from 0 to 1")
+	searchCmd.Flags().Bool("explain", false, "Show how each result was scored")
+	addFormatFlag(searchCmd)
 	rootCmd.AddCommand(searchCmd)
 }
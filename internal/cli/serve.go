@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/NOTAschool/gqmd/internal/serve"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the daemon: scheduled scans and MCP over HTTP",
+	Long: `Run gqmd as a long-lived daemon. Each collection is scanned, and
+optionally embedded, on its own schedule, and the MCP tools are served over
+HTTP at /mcp. Logs are written to stderr. Send SIGHUP to reload the config.
+
+The config file is JSON, by default $XDG_CONFIG_HOME/gqmd/serve.json:
+
+  {
+    "listen": "127.0.0.1:8765",
+    "interval": "1h",
+    "embed": true,
+    "collections": {
+      "notes": {"interval": "10m"},
+      "archive": {"disabled": true}
+    }
+  }`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath, err := resolveDBPath()
+		if err != nil {
+			return err
+		}
+
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			if configPath, err = serve.DefaultConfigPath(); err != nil {
+				return err
+			}
+		}
+
+		logFormat, _ := cmd.Flags().GetString("log-format")
+		var handler slog.Handler
+		switch logFormat {
+		case "json":
+			handler = slog.NewJSONHandler(os.Stderr, nil)
+		case "text":
+			handler = slog.NewTextHandler(os.Stderr, nil)
+		default:
+			return fmt.Errorf("unknown log format %q, want json or text", logFormat)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return serve.Run(ctx, serve.Options{
+			ConfigPath: configPath,
+			DBPath:     dbPath,
+			Logger:     slog.New(handler),
+		})
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringP("config", "c", "", "Config file (default $XDG_CONFIG_HOME/gqmd/serve.json)")
+	serveCmd.Flags().String("log-format", "json", "Log format: json or text")
+	rootCmd.AddCommand(serveCmd)
+}
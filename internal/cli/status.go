@@ -2,7 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/NOTAschool/gqmd/internal/embed"
 	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/spf13/cobra"
 )
@@ -10,25 +12,60 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show index status",
-	Long:  `Show the status of the gqmd index.`,
+	Long: `Show the status of the gqmd index: document and collection counts, sizes,
+last scan per collection and embedding coverage per model. --json prints the
+full structure; other formats print the summary row.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		db, err := store.Open()
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			format = formatJSON
+		}
+		model, _ := cmd.Flags().GetString("model")
+		if model == "" {
+			model = embed.NewClientFromEnv("").Model()
+		}
+
+		db, err := openStore()
 		if err != nil {
 			return fmt.Errorf("failed to open database: %w", err)
 		}
 		defer db.Close()
 
-		status, err := db.GetStatus()
+		status, err := db.GetStatusWithOptions(store.StatusOptions{Model: model})
 		if err != nil {
 			return fmt.Errorf("failed to get status: %w", err)
 		}
 
-		fmt.Println("gqmd Index Status:")
-		fmt.Printf("  Database: %s\n", status.DBPath)
-		fmt.Printf("  Total documents: %d\n", status.TotalDocs)
-		fmt.Printf("  Collections: %d\n", status.Collections)
-		fmt.Printf("  Has vector index: %v\n", status.HasVectorIndex)
-
-		return nil
+		switch format {
+		case formatText:
+			fmt.Println(status)
+			return nil
+		case formatJSON:
+			return writeJSON(os.Stdout, status)
+		default:
+			return render(os.Stdout, format, statusTable(status))
+		}
 	},
 }
+
+// statusTable is the one-row summary of a status
+func statusTable(status *store.Status) *table {
+	return &table{
+		name: "status",
+		columns: []string{"db_path", "total_docs", "collections", "has_vector_index",
+			"content_bytes", "db_bytes", "fts_bytes", "needs_embedding"},
+		rows: [][]any{{status.DBPath, status.TotalDocs, status.Collections, status.HasVectorIndex,
+			status.ContentBytes, status.DBBytes, status.FTSBytes, status.NeedsEmbedding}},
+		pathCol: -1,
+		single:  true,
+	}
+}
+
+func init() {
+	addFormatFlag(statusCmd)
+	statusCmd.Flags().Bool("json", false, "Print the full status as JSON (same as --format json)")
+	statusCmd.Flags().StringP("model", "m", "", "Count documents needing embedding for this model (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+}
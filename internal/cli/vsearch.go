@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var vsearchCmd = &cobra.Command{
+	Use:   "vsearch <query>",
+	Short: "Semantic search documents",
+	Long:  `Search documents by vector similarity using Ollama embeddings.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := args[0]
+		var opts store.SearchOptions
+		opts.Limit, _ = cmd.Flags().GetInt("limit")
+		opts.Offset, _ = cmd.Flags().GetInt("offset")
+		opts.Cursor, _ = cmd.Flags().GetString("cursor")
+		opts.MinScore, _ = cmd.Flags().GetFloat64("min-score")
+		opts.Explain, _ = cmd.Flags().GetBool("explain")
+		opts.SnippetLength, _ = cmd.Flags().GetInt("snippet-length")
+		opts.Collapse, _ = cmd.Flags().GetBool("collapse")
+		model, _ := cmd.Flags().GetString("model")
+		format, err := getFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		client := embed.NewClientFromEnv(model)
+		queryVec, err := client.Embed(query)
+		if err != nil {
+			return fmt.Errorf("embedding failed: %w", err)
+		}
+		opts.Model = client.Model()
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		results, page, err := db.VectorSearchWithOptions(store.Vector(queryVec), opts)
+		if err != nil {
+			return err
+		}
+
+		if page.HasMore {
+			fmt.Fprintf(os.Stderr, "More results: --cursor %s\n", page.NextCursor)
+		}
+
+		if format != formatText {
+			return render(os.Stdout, format, vectorTable(results))
+		}
+
+		if len(results) == 0 {
+			fmt.Println("No results found")
+			return nil
+		}
+
+		for i, r := range results {
+			fmt.Printf("%d. %s/%s %s (%.3f)\n", i+1, r.Collection, r.Path, r.DocID, r.Score)
+			fmt.Printf("   %s\n", r.Title)
+			if r.Context != "" {
+				fmt.Printf("   Context: %s\n", r.Context)
+			}
+			if r.Explanation != nil {
+				fmt.Printf("   Explain: %s\n", r.Explanation)
+			}
+			if r.Text != "" {
+				fmt.Printf("   Lines %d-%d %s\n   %s\n", r.FromLine, r.ToLine, r.Breadcrumb, r.Text)
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+func vectorTable(results []store.VectorResult) *table {
+	t := &table{
+		name:    "result",
+		columns: []string{"path", "docid", "title", "score", "context", "chunk", "from_line", "to_line", "breadcrumb", "text"},
+		pathCol: 0,
+	}
+	explain := len(results) > 0 && results[0].Explanation != nil
+	if explain {
+		t.columns = append(t.columns, "rank")
+	}
+	for _, r := range results {
+		row := []any{r.Collection + "/" + r.Path, r.DocID, r.Title, r.Score, r.Context,
+			r.ChunkIdx, r.FromLine, r.ToLine, r.Breadcrumb, r.Text}
+		if explain {
+			row = append(row, r.Explanation.Rank)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+func init() {
+	vsearchCmd.Flags().IntP("limit", "n", 10, "Max results")
+	vsearchCmd.Flags().Int("offset", 0, "Number of results to skip")
+	vsearchCmd.Flags().String("cursor", "", "Cursor from a previous search to fetch the next page")
+	vsearchCmd.Flags().Float64("min-score", 0, "Minimum normalized score from 0 to 1")
+	vsearchCmd.Flags().Bool("explain", false, "Show how each result was scored")
+	vsearchCmd.Flags().Int("snippet-length", store.DefaultSnippetLength, "Bytes of matching chunk text per hit, -1 for all")
+	vsearchCmd.Flags().Bool("collapse", false, "Show only the best matching chunk of each document")
+	vsearchCmd.Flags().StringP("model", "m", "", "Embedding model (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+	addFormatFlag(vsearchCmd)
+	rootCmd.AddCommand(vsearchCmd)
+}
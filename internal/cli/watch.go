@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Reindex files as they change",
+	Long: `Watch all collection directories and reindex files as they are created,
+edited, renamed or deleted. Bursts of edits are debounced. Collections added
+or removed while watching are picked up within a few seconds; run scan on a
+new collection to index the files it already has.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := watchOptions(cmd)
+		opts.Logf = func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		}
+
+		db, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		w, err := watch.New(db, opts)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Println("Watching collections, press Ctrl+C to stop")
+		return w.Run(ctx)
+	},
+}
+
+// addWatchFlags registers the flags shared by watch and mcp --watch
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("debounce", watch.DefaultDebounce, "Wait this long for edits to settle before reindexing")
+	cmd.Flags().Bool("embed", false, "Embed changed documents via Ollama")
+	cmd.Flags().StringP("model", "m", "", "Embedding model (default $GQMD_EMBEDDING_MODEL or nomic-embed-text)")
+}
+
+func watchOptions(cmd *cobra.Command) watch.Options {
+	var opts watch.Options
+	opts.Debounce, _ = cmd.Flags().GetDuration("debounce")
+	if withEmbed, _ := cmd.Flags().GetBool("embed"); withEmbed {
+		model, _ := cmd.Flags().GetString("model")
+		client := embed.NewClientFromEnv(model)
+		opts.Embed = client.Embed
+		opts.Model = client.Model()
+	}
+	return opts
+}
+
+func init() {
+	addWatchFlags(watchCmd)
+	rootCmd.AddCommand(watchCmd)
+}
@@ -2,9 +2,12 @@ package embed
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 )
 
 // Client is an embedding client
@@ -29,6 +32,24 @@ func NewClient(baseURL, model string) *Client {
 	}
 }
 
+// NewClientFromEnv creates a client for the Ollama at $OLLAMA_HOST. An
+// empty model falls back to $GQMD_EMBEDDING_MODEL.
+func NewClientFromEnv(model string) *Client {
+	host := os.Getenv("OLLAMA_HOST")
+	if host != "" && !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	if model == "" {
+		model = os.Getenv("GQMD_EMBEDDING_MODEL")
+	}
+	return NewClient(host, model)
+}
+
+// Model returns the embedding model name
+func (c *Client) Model() string {
+	return c.model
+}
+
 type embedRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
@@ -38,6 +59,29 @@ type embedResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
+// StatusError is an error response from Ollama, such as for a model that
+// is not pulled
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ollama returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ollama returned %d: %s", e.StatusCode, e.Message)
+}
+
+// statusError reads the error message of a failed response
+func statusError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return &StatusError{StatusCode: resp.StatusCode, Message: body.Error}
+}
+
 // Embed generates embedding for text
 func (c *Client) Embed(text string) ([]float32, error) {
 	reqBody := embedRequest{
@@ -61,7 +105,7 @@ func (c *Client) Embed(text string) ([]float32, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama returned %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	var result embedResponse
@@ -71,3 +115,37 @@ func (c *Client) Embed(text string) ([]float32, error) {
 
 	return result.Embedding, nil
 }
+
+type tagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// Ping checks that Ollama is reachable and has the model pulled
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	var tags tagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return err
+	}
+	for _, m := range tags.Models {
+		if m.Name == c.model || m.Name == c.model+":latest" {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q is not pulled in ollama at %s", c.model, c.baseURL)
+}
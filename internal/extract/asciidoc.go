@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AsciiDoc extracts AsciiDoc documents
+type AsciiDoc struct{}
+
+var (
+	adocHeadingRe   = regexp.MustCompile(`^(={1,6})\s+(\S.*?)\s*=*$`)
+	adocAttributeRe = regexp.MustCompile(`^:!?[\w-]+!?:`)
+	adocBlockAttrRe = regexp.MustCompile(`^\[.*\]$`)
+	adocDelimiterRe = regexp.MustCompile(`^(-{4,}|\.{4,}|={4,}|\*{4,}|_{4,}|\+{4,}|\|={3,}|-{2})$`)
+	adocURLRe       = regexp.MustCompile(`(?:link:)?((?:https?://|mailto:)?[^\s\[\]]+)\[([^\]]*)\]`)
+	adocXrefRe      = regexp.MustCompile(`<<([^,>]+)(?:,\s*([^>]+))?>>`)
+)
+
+// Extract converts "=" section titles, uses the document title ("= Title")
+// as the title, keeps the text of links and cross references and drops
+// attributes, block delimiters and comments
+func (AsciiDoc) Extract(path string, content []byte) (*Document, error) {
+	var w writer
+	var title string
+	comment := false
+
+	for _, line := range SplitLines(string(content)) {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "////" {
+			comment = !comment
+			continue
+		}
+		if comment || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		if m := adocHeadingRe.FindStringSubmatch(line); m != nil {
+			text := w.adocInline(m[2])
+			if len(m[1]) == 1 && title == "" {
+				title = text
+			}
+			w.heading(len(m[1]), text)
+			continue
+		}
+
+		switch {
+		case adocAttributeRe.MatchString(trimmed),
+			adocBlockAttrRe.MatchString(trimmed),
+			adocDelimiterRe.MatchString(trimmed):
+			continue
+		case len(trimmed) > 1 && trimmed[0] == '.' && trimmed[1] != '.' && trimmed[1] != ' ':
+			// Block title
+			w.line(w.adocInline(trimmed[1:]))
+			continue
+		}
+		w.line(w.adocInline(line))
+	}
+	return w.document(title, path), nil
+}
+
+// adocInline replaces links and cross references with their text
+func (w *writer) adocInline(line string) string {
+	line = adocXrefRe.ReplaceAllStringFunc(line, func(s string) string {
+		m := adocXrefRe.FindStringSubmatch(s)
+		w.link(m[2], "#"+m[1])
+		if m[2] != "" {
+			return m[2]
+		}
+		return m[1]
+	})
+	return adocURLRe.ReplaceAllStringFunc(line, func(s string) string {
+		m := adocURLRe.FindStringSubmatch(s)
+		// Only links to URLs or other documents, not macros like image:
+		if !strings.HasPrefix(s, "link:") && !strings.Contains(m[1], "://") && !strings.HasPrefix(m[1], "mailto:") {
+			return s
+		}
+		w.link(m[2], m[1])
+		if m[2] != "" {
+			return m[2]
+		}
+		return m[1]
+	})
+}
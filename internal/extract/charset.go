@@ -0,0 +1,132 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// ErrBinary is returned by Decode for content that is not text
+var ErrBinary = errors.New("binary content")
+
+// sniffLen is how much of a file is inspected to tell text from binary
+const sniffLen = 8000
+
+// Decode converts text to UTF-8. It detects UTF-8 and UTF-16 by byte
+// order mark or content, then GBK, and falls back to Latin-1
+// (Windows-1252), which accepts any bytes. It returns the converted text
+// and the detected charset, or ErrBinary.
+func Decode(content []byte) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return content[3:], "utf-8", nil
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), content, "utf-16le")
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), content, "utf-16be")
+	}
+
+	if order, ok := sniffUTF16(content); ok {
+		name := "utf-16le"
+		if order == unicode.BigEndian {
+			name = "utf-16be"
+		}
+		return decodeWith(unicode.UTF16(order, unicode.IgnoreBOM), content, name)
+	}
+	if isBinary(content) {
+		return nil, "", ErrBinary
+	}
+
+	switch {
+	case utf8.Valid(content):
+		return content, "utf-8", nil
+	case isGBK(content):
+		return decodeWith(simplifiedchinese.GBK, content, "gbk")
+	default:
+		return decodeWith(charmap.Windows1252, content, "latin-1")
+	}
+}
+
+func decodeWith(enc encoding.Encoding, content []byte, name string) ([]byte, string, error) {
+	out, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, "", err
+	}
+	if isBinary(out) {
+		return nil, "", ErrBinary
+	}
+	return out, name, nil
+}
+
+// isBinary reports whether the start of content holds a NUL byte or
+// mostly control characters
+func isBinary(content []byte) bool {
+	sample := content[:min(len(content), sniffLen)]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	control := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != '\b' && b != 0x1B {
+			control++
+		}
+	}
+	return control > len(sample)/10
+}
+
+// sniffUTF16 detects UTF-16 without a byte order mark from the NUL high
+// bytes of ASCII characters
+func sniffUTF16(content []byte) (unicode.Endianness, bool) {
+	sample := content[:min(len(content), sniffLen)&^1]
+	if len(sample) < 4 {
+		return unicode.LittleEndian, false
+	}
+	var even, odd int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case odd > pairs*2/5 && even < pairs/10:
+		return unicode.LittleEndian, true
+	case even > pairs*2/5 && odd < pairs/10:
+		return unicode.BigEndian, true
+	}
+	return unicode.LittleEndian, false
+}
+
+// isGBK reports whether content is valid GBK whose double-byte characters
+// mostly fall in the GB2312 range. Latin-1 text rarely does, as its high
+// bytes stand alone between ASCII letters.
+func isGBK(content []byte) bool {
+	var pairs, gb2312 int
+	for i := 0; i < len(content); i++ {
+		lead := content[i]
+		if lead < 0x80 {
+			continue
+		}
+		if lead == 0x80 || lead == 0xFF || i+1 >= len(content) {
+			return false
+		}
+		trail := content[i+1]
+		if trail < 0x40 || trail == 0x7F || trail == 0xFF {
+			return false
+		}
+		pairs++
+		if lead >= 0xA1 && lead <= 0xF7 && trail >= 0xA1 {
+			gb2312++
+		}
+		i++
+	}
+	return pairs > 0 && gb2312*5 >= pairs*4
+}
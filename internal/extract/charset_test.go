@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	const chinese = "# 笔记\n\n这是一个测试文件，包含中文内容。\n"
+	const latin = "# Café notes\n\nNaïve résumé, ÉCOLE déjà vu.\n"
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+		charset string
+	}{
+		{"utf-8", []byte(chinese), chinese, "utf-8"},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, chinese...), chinese, "utf-8"},
+		{"utf-16le bom", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), latin), latin, "utf-16le"},
+		{"utf-16be bom", encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), chinese), chinese, "utf-16be"},
+		{"utf-16le", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), latin), latin, "utf-16le"},
+		{"gbk", encode(t, simplifiedchinese.GBK, chinese), chinese, "gbk"},
+		{"latin-1", encode(t, charmap.Windows1252, latin), latin, "latin-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, charset, err := Decode(tt.content)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Decode = %q, want %q", got, tt.want)
+			}
+			if charset != tt.charset {
+				t.Errorf("charset = %q, want %q", charset, tt.charset)
+			}
+		})
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	binaries := map[string][]byte{
+		"png":     {0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 0x0D, 'I', 'H', 'D', 'R'},
+		"control": []byte("\x01\x02\x03\x04\x05\x06\x07abc"),
+	}
+	for name, content := range binaries {
+		if _, _, err := Decode(content); !errors.Is(err, ErrBinary) {
+			t.Errorf("%s: err = %v, want ErrBinary", name, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package extract turns documents of different formats into indexable
+// text. Extractors are registered by file extension; files with no
+// registered extractor are treated as markdown.
+//
+// Extracted text is plain text with headings written as markdown ATX
+// lines ("## Setup"), so sections, outlines and chunking work the same
+// for every format.
+package extract
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Document is the indexable form of a file
+type Document struct {
+	Title    string
+	Text     string
+	Headings []Heading
+	Links    []Link
+}
+
+// Heading is a section heading. Line is 1-based and refers to Text.
+type Heading struct {
+	Level int
+	Text  string
+	Line  int
+}
+
+// Link is a hyperlink or cross reference found in a document
+type Link struct {
+	Text   string
+	Target string
+}
+
+// Extractor converts the content of one file format. The path is relative
+// to the collection root and is used as a title fallback.
+type Extractor interface {
+	Extract(path string, content []byte) (*Document, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Extractor{}
+)
+
+func init() {
+	Register(Markdown{}, ".md", ".markdown", ".mdx")
+	Register(Org{}, ".org")
+	Register(RST{}, ".rst", ".rest")
+	Register(AsciiDoc{}, ".adoc", ".asciidoc", ".asc")
+	Register(Text{}, ".txt", ".text")
+	Register(HTML{}, ".html", ".htm", ".xhtml")
+	Register(Notebook{}, ".ipynb")
+	Register(GoDoc{}, ".go")
+	Register(Python{}, ".py", ".pyi")
+}
+
+// Register makes an extractor handle files with the given extensions,
+// e.g. ".org". Extensions are matched case-insensitively and a later
+// registration replaces an earlier one.
+func Register(e Extractor, exts ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ext := range exts {
+		registry[strings.ToLower(ext)] = e
+	}
+}
+
+// For returns the extractor for a path, falling back to markdown
+func For(path string) Extractor {
+	mu.RLock()
+	defer mu.RUnlock()
+	if e, ok := registry[strings.ToLower(filepath.Ext(path))]; ok {
+		return e
+	}
+	return Markdown{}
+}
+
+// Extensions lists the registered extensions in order
+func Extensions() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	exts := make([]string, 0, len(registry))
+	for ext := range registry {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Extract converts a file with the extractor registered for its extension
+func Extract(path string, content []byte) (*Document, error) {
+	return For(path).Extract(path, content)
+}
+
+// baseName is the file name without its extension, the title of last resort
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+var urlRe = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// bareURLs returns URLs appearing in plain text
+func bareURLs(text string) []Link {
+	var links []Link
+	for _, u := range urlRe.FindAllString(text, -1) {
+		u = strings.TrimRight(u, ".,;:!?")
+		links = append(links, Link{Text: u, Target: u})
+	}
+	return links
+}
+
+// writer builds extracted text line by line, recording headings and
+// collapsing runs of blank lines
+type writer struct {
+	lines    []string
+	headings []Heading
+	links    []Link
+}
+
+func (w *writer) line(s string) {
+	s = strings.TrimRight(s, " \t\r")
+	// Text that reads as a heading would start a section
+	if _, ok := parseHeading(strings.TrimSpace(s)); ok {
+		s = strings.Replace(s, "#", `\#`, 1)
+	}
+	if s == "" && (len(w.lines) == 0 || w.lines[len(w.lines)-1] == "") {
+		return
+	}
+	w.lines = append(w.lines, s)
+}
+
+func (w *writer) heading(level int, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	level = min(max(level, 1), 6)
+	if len(w.lines) > 0 {
+		w.line("")
+	}
+	w.lines = append(w.lines, strings.Repeat("#", level)+" "+text)
+	w.headings = append(w.headings, Heading{Level: level, Text: text, Line: len(w.lines)})
+	w.lines = append(w.lines, "")
+}
+
+// code writes a fenced code block, fenced with tildes if the code holds
+// backtick fences itself
+func (w *writer) code(lang, code string) {
+	code = strings.Trim(code, "\n")
+	if strings.TrimSpace(code) == "" {
+		return
+	}
+	fence := "```"
+	if strings.Contains(code, fence) {
+		fence = "~~~"
+	}
+	w.lines = append(w.lines, fence+lang)
+	w.lines = append(w.lines, strings.Split(code, "\n")...)
+	w.lines = append(w.lines, fence, "")
+}
+
+func (w *writer) link(text, target string) {
+	if target == "" {
+		return
+	}
+	if text == "" {
+		text = target
+	}
+	w.links = append(w.links, Link{Text: text, Target: target})
+}
+
+// document finishes the text. The title is the given one, else the first
+// top-level heading, else the first heading, else the file name.
+func (w *writer) document(title, path string) *Document {
+	for len(w.lines) > 0 && w.lines[len(w.lines)-1] == "" {
+		w.lines = w.lines[:len(w.lines)-1]
+	}
+	doc := &Document{Headings: w.headings, Links: w.links}
+	if len(w.lines) > 0 {
+		doc.Text = strings.Join(w.lines, "\n") + "\n"
+	}
+
+	doc.Title = strings.TrimSpace(title)
+	if doc.Title == "" {
+		top := 0
+		for i, h := range w.headings {
+			if h.Level < w.headings[top].Level {
+				top = i
+			}
+		}
+		if len(w.headings) > 0 {
+			doc.Title = w.headings[top].Text
+		}
+	}
+	if doc.Title == "" {
+		doc.Title = baseName(path)
+	}
+	return doc
+}
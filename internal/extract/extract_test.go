@@ -0,0 +1,200 @@
+package extract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	tests := map[string]Extractor{
+		"a.md":        Markdown{},
+		"notes/b.ORG": Org{},
+		"c.rst":       RST{},
+		"d.adoc":      AsciiDoc{},
+		"e.txt":       Text{},
+		"f.html":      HTML{},
+		"g.ipynb":     Notebook{},
+		"h.go":        GoDoc{},
+		"i.py":        Python{},
+		"j.unknown":   Markdown{},
+	}
+	for path, want := range tests {
+		if got := For(path); got != want {
+			t.Errorf("For(%q) = %T, want %T", path, got, want)
+		}
+	}
+}
+
+// headingTexts lists headings as markdown lines, e.g. "## Setup"
+func headingTexts(hs []Heading) []string {
+	var out []string
+	for _, h := range hs {
+		out = append(out, strings.Repeat("#", h.Level)+" "+h.Text)
+	}
+	return out
+}
+
+func TestExtractFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		title    string
+		headings []string
+		links    []Link
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "markdown",
+			path: "a.md",
+			content: "# Guide\nSee [docs](https://example.com/docs) and <https://go.dev>.\n" +
+				"## Setup\n![logo](logo.png)\n```\n[not](a-link)\n```\n",
+			title:    "Guide",
+			headings: []string{"# Guide", "## Setup"},
+			links: []Link{
+				{"docs", "https://example.com/docs"},
+				{"https://go.dev", "https://go.dev"},
+			},
+		},
+		{
+			name: "org",
+			path: "notes.org",
+			content: "#+TITLE: Project Notes\n#+AUTHOR: me\n* TODO [#A] Planning :work:\n" +
+				":PROPERTIES:\n:ID: 123\n:END:\nRead [[https://orgmode.org][the manual]].\n" +
+				"** Details\n#+BEGIN_SRC go\nfmt.Println()\n#+END_SRC\n# a comment\n",
+			title:    "Project Notes",
+			headings: []string{"# Planning", "## Details"},
+			links:    []Link{{"the manual", "https://orgmode.org"}},
+			contains: []string{"# Planning\n", "Read the manual.", "fmt.Println()"},
+			excludes: []string{"AUTHOR", ":ID:", "BEGIN_SRC", "a comment", ":work:"},
+		},
+		{
+			name: "rst",
+			path: "guide.rst",
+			content: "=====\nGuide\n=====\n\nIntro with `a link <https://docutils.org>`_.\n\n" +
+				"Install\n-------\n\n.. note:: Use ``pip``.\n\n.. a comment\n\nUsage\n-----\n\n" +
+				"Options\n~~~~~~~\n\nSee :ref:`config <cfg>`.\n\n.. _docs: https://example.com\n",
+			title:    "Guide",
+			headings: []string{"# Guide", "## Install", "## Usage", "### Options"},
+			links: []Link{
+				{"a link", "https://docutils.org"},
+				{"docs", "https://example.com"},
+			},
+			contains: []string{"Intro with a link.", "Use pip.", "See config."},
+			excludes: []string{"a comment", "-----"},
+		},
+		{
+			name: "asciidoc",
+			path: "guide.adoc",
+			content: "= User Guide\n:toc:\n\n== Install\n\n[source,sh]\n----\nmake\n----\n\n" +
+				"See https://asciidoctor.org[Asciidoctor] and <<usage,Usage>>.\n\n" +
+				"=== Linux\n\n// a comment\n.Example\ntext\n",
+			title:    "User Guide",
+			headings: []string{"# User Guide", "## Install", "### Linux"},
+			links: []Link{
+				{"Usage", "#usage"},
+				{"Asciidoctor", "https://asciidoctor.org"},
+			},
+			contains: []string{"make", "See Asciidoctor and Usage.", "Example"},
+			excludes: []string{":toc:", "[source", "----", "a comment"},
+		},
+		{
+			name:     "text",
+			path:     "readme.txt",
+			content:  "\nMeeting notes\n\nDetails at https://example.com/x.\n",
+			title:    "Meeting notes",
+			links:    []Link{{"https://example.com/x", "https://example.com/x"}},
+			contains: []string{"Meeting notes"},
+		},
+		{
+			name: "html",
+			path: "page.html",
+			content: "<html><head><title>The Page</title><style>p{}</style></head><body>" +
+				"<h1>Welcome</h1><p>Hello <a href=\"/about\">about   us</a>.</p>" +
+				"<script>alert(1)</script><h2>List</h2><ul><li>one</li><li>two</li></ul>" +
+				"<pre>a\n  b</pre></body></html>",
+			title:    "The Page",
+			headings: []string{"# Welcome", "## List"},
+			links:    []Link{{"about us", "/about"}},
+			contains: []string{"# Welcome\n", "Hello about us.", "- one\n- two", "a\n  b"},
+			excludes: []string{"alert", "p{}", "<"},
+		},
+		{
+			name: "notebook",
+			path: "analysis.ipynb",
+			content: `{"metadata": {"language_info": {"name": "python"}}, "cells": [
+				{"cell_type": "markdown", "source": ["# Analysis\n", "Load [data](https://example.com/d.csv).\n"]},
+				{"cell_type": "code", "source": "import pandas as pd\ndf = pd.read_csv('d.csv')",
+				 "outputs": [{"output_type": "stream", "text": ["SECRET OUTPUT"]}]},
+				{"cell_type": "markdown", "source": "## Plot"},
+				{"cell_type": "code", "source": ["df.plot()"], "outputs": []}
+			]}`,
+			title:    "Analysis",
+			headings: []string{"# Analysis", "## Code cell 1", "## Plot", "### Code cell 2"},
+			links:    []Link{{"data", "https://example.com/d.csv"}},
+			contains: []string{"```python\nimport pandas as pd\n", "df.plot()"},
+			excludes: []string{"SECRET OUTPUT", "outputs"},
+		},
+		{
+			name: "go",
+			path: "internal/store/scan.go",
+			content: "// Package store keeps the index.\n//\n// # Usage\n//\n//\t# a shell comment\n//\tgqmd scan\npackage store\n\nimport \"os\"\n\n" +
+				"// Limit caps scans.\nconst Limit = 10\n\n" +
+				"// Store is an index.\ntype Store struct{ path string }\n\n" +
+				"// Scan walks a tree.\n// It returns a count.\nfunc (s *Store) Scan(dir string) (int, error) {\n\treturn secretBody(os.Args)\n}\n\n" +
+				"func undocumented() {}\n",
+			title:    "package store (scan.go)",
+			headings: []string{"# package store", "## Usage", "## const Limit", "## type Store", "## func Store.Scan"},
+			contains: []string{"Package store keeps the index.", "func (s *Store) Scan(dir string) (int, error)\n", "Scan walks a tree.\nIt returns a count.", "\t\\# a shell comment"},
+			excludes: []string{"secretBody", "undocumented", "import"},
+		},
+		{
+			name: "python",
+			path: "pkg/tools.py",
+			content: "#!/usr/bin/env python\n\"\"\"Tools for testing.\n\nMore words.\n\"\"\"\nimport os\n\n" +
+				"def helper(a,\n           b):  # comment\n    '''Add two numbers.'''\n    return secret_body(a, b)\n\n" +
+				"class Thing:\n    \"\"\"A thing.\n\n    With details.\n    \"\"\"\n\n" +
+				"    def run(self):\n        \"\"\"Run it.\"\"\"\n\n    def quiet(self):\n        pass\n",
+			title:    "Tools for testing.",
+			headings: []string{"## def helper", "## class Thing", "### def Thing.run"},
+			contains: []string{"Tools for testing.\n\nMore words.", "def helper(a,\n           b):", "A thing.\n\nWith details.", "Run it."},
+			excludes: []string{"secret_body", "quiet", "import os"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Extract(tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if doc.Title != tt.title {
+				t.Errorf("Title = %q, want %q", doc.Title, tt.title)
+			}
+			if got := headingTexts(doc.Headings); !reflect.DeepEqual(got, tt.headings) {
+				t.Errorf("Headings = %q, want %q", got, tt.headings)
+			}
+			if !reflect.DeepEqual(doc.Links, tt.links) {
+				t.Errorf("Links = %+v, want %+v", doc.Links, tt.links)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(doc.Text, s) {
+					t.Errorf("Text lacks %q:\n%s", s, doc.Text)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(doc.Text, s) {
+					t.Errorf("Text contains %q:\n%s", s, doc.Text)
+				}
+			}
+
+			// Headings point at their lines, so the markdown section
+			// parser sees the same structure
+			if got := ParseHeadings(doc.Text); !reflect.DeepEqual(got, doc.Headings) {
+				t.Errorf("ParseHeadings(Text) = %+v, want %+v", got, doc.Headings)
+			}
+		})
+	}
+}
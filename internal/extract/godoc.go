@@ -0,0 +1,120 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// GoDoc extracts the documentation of Go source files: the package
+// comment, then one section per documented declaration with its
+// signature and doc comment. Function bodies are left out.
+type GoDoc struct{}
+
+// Extract titles the file "package name (file.go)"
+func (GoDoc) Extract(path string, content []byte) (*Document, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var w writer
+	title := fmt.Sprintf("package %s (%s)", file.Name.Name, filepath.Base(path))
+	w.heading(1, "package "+file.Name.Name)
+	w.comment(file.Doc, 1)
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc == nil {
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverType(d.Recv.List[0].Type) + "." + name
+			}
+			w.heading(2, "func "+name)
+			w.code("go", node(fset, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type}))
+			w.comment(d.Doc, 2)
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				doc, names := specDoc(spec)
+				// A lone spec is documented by its declaration's comment
+				if doc == nil && len(d.Specs) == 1 {
+					doc = d.Doc
+				}
+				if doc == nil {
+					continue
+				}
+				w.heading(2, d.Tok.String()+" "+strings.Join(names, ", "))
+				w.code("go", d.Tok.String()+" "+node(fset, spec))
+				w.comment(doc, 2)
+			}
+		}
+	}
+	return w.document(title, path), nil
+}
+
+// comment writes the text of a doc comment in a section of the given
+// level; doc comment headings ("# Usage") become subsections
+func (w *writer) comment(doc *ast.CommentGroup, level int) {
+	if doc == nil {
+		return
+	}
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if text, ok := strings.CutPrefix(line, "# "); ok {
+			w.heading(level+1, text)
+			continue
+		}
+		w.line(line)
+	}
+	w.line("")
+}
+
+func specDoc(spec ast.Spec) (*ast.CommentGroup, []string) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc, []string{s.Name.Name}
+	case *ast.ValueSpec:
+		names := make([]string, len(s.Names))
+		for i, n := range s.Names {
+			names[i] = n.Name
+		}
+		return s.Doc, names
+	}
+	return nil, nil
+}
+
+// receiverType names a method's receiver type without pointer or type
+// parameters
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+func node(fset *token.FileSet, n any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,164 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTML extracts the visible text of HTML pages
+type HTML struct{}
+
+// htmlBlocks are elements that start a new line
+var htmlBlocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Dd: true, atom.Details: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.Form: true, atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Summary: true, atom.Table: true, atom.Td: true, atom.Th: true, atom.Tr: true,
+	atom.Ul: true,
+}
+
+// htmlLines are blocks that end a line but not a paragraph
+var htmlLines = map[atom.Atom]bool{
+	atom.Br: true, atom.Dd: true, atom.Dt: true, atom.Li: true, atom.Td: true,
+	atom.Th: true, atom.Tr: true,
+}
+
+// htmlSkipped are elements whose content is not text
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Head: true,
+}
+
+var htmlHeadings = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// Extract converts h1-h6 to headings and block elements to lines, uses
+// <title> as the title and drops scripts, styles and markup
+func (HTML) Extract(path string, content []byte) (*Document, error) {
+	var (
+		w       writer
+		title   strings.Builder
+		cur     strings.Builder // inline text of the current line
+		inTitle bool
+		skip    int // depth inside skipped elements
+		pre     int // depth inside <pre>
+		heading int // level of the open heading, or 0
+		href    string
+		anchor  strings.Builder // text of the open link
+		inLink  bool
+	)
+
+	flush := func() {
+		text := cur.String()
+		cur.Reset()
+		if strings.TrimSpace(text) == "" || text == "- " {
+			return
+		}
+		if heading > 0 {
+			w.heading(heading, text)
+			return
+		}
+		if pre > 0 {
+			for _, line := range strings.Split(text, "\n") {
+				w.line(line)
+			}
+			return
+		}
+		w.line(strings.Join(strings.Fields(text), " "))
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			return nil, z.Err()
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch {
+			case tok.DataAtom == atom.Title:
+				inTitle = tt == html.StartTagToken
+			case htmlSkipped[tok.DataAtom]:
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case htmlHeadings[tok.DataAtom] > 0:
+				flush()
+				heading = htmlHeadings[tok.DataAtom]
+			case tok.DataAtom == atom.A:
+				href, inLink = attr(tok, "href"), true
+				anchor.Reset()
+			case htmlBlocks[tok.DataAtom]:
+				flush()
+				if tok.DataAtom == atom.Pre {
+					pre++
+				}
+				if tok.DataAtom == atom.Li {
+					cur.WriteString("- ")
+				}
+			}
+
+		case html.EndTagToken:
+			switch {
+			case tok.DataAtom == atom.Title:
+				inTitle = false
+			case htmlSkipped[tok.DataAtom]:
+				if skip > 0 {
+					skip--
+				}
+			case htmlHeadings[tok.DataAtom] > 0:
+				flush()
+				heading = 0
+			case tok.DataAtom == atom.A:
+				if inLink && !strings.HasPrefix(href, "javascript:") {
+					w.link(strings.Join(strings.Fields(anchor.String()), " "), href)
+				}
+				inLink = false
+			case htmlBlocks[tok.DataAtom]:
+				flush()
+				if tok.DataAtom == atom.Pre && pre > 0 {
+					pre--
+				}
+				if !htmlLines[tok.DataAtom] {
+					w.line("")
+				}
+			}
+
+		case html.TextToken:
+			switch {
+			case inTitle:
+				title.WriteString(tok.Data)
+			case skip > 0:
+			default:
+				cur.WriteString(tok.Data)
+				if inLink {
+					anchor.WriteString(tok.Data)
+				}
+			}
+		}
+	}
+	flush()
+
+	return w.document(strings.Join(strings.Fields(title.String()), " "), path), nil
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
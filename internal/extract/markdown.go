@@ -0,0 +1,132 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Markdown indexes markdown as is
+type Markdown struct{}
+
+var (
+	mdLinkRe     = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdAutolinkRe = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+)
+
+// Extract returns the content unchanged, titled by a "# " heading in the
+// first lines
+func (Markdown) Extract(path string, content []byte) (*Document, error) {
+	text := string(content)
+	doc := &Document{
+		Title:    markdownTitle(text, path),
+		Text:     text,
+		Headings: ParseHeadings(text),
+	}
+
+	for _, line := range outsideFences(text) {
+		for _, m := range mdLinkRe.FindAllStringSubmatch(line, -1) {
+			if m[1] == "" {
+				doc.Links = append(doc.Links, Link{Text: m[2], Target: m[3]})
+			}
+		}
+		for _, m := range mdAutolinkRe.FindAllStringSubmatch(line, -1) {
+			doc.Links = append(doc.Links, Link{Text: m[1], Target: m[1]})
+		}
+	}
+	return doc, nil
+}
+
+func markdownTitle(content, path string) string {
+	lines := strings.SplitN(content, "\n", 3)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimPrefix(line, "# ")
+		}
+	}
+	return baseName(path)
+}
+
+// ParseHeadings returns the ATX headings of a markdown document,
+// ignoring lines inside fenced code blocks
+func ParseHeadings(content string) []Heading {
+	var headings []Heading
+	var fence string
+
+	for i, line := range SplitLines(content) {
+		trimmed := strings.TrimSpace(line)
+
+		// Track fenced code blocks
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:3]
+			if fence == "" {
+				fence = marker
+			} else if fence == marker {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if h, ok := parseHeading(trimmed); ok {
+			h.Line = i + 1
+			headings = append(headings, h)
+		}
+	}
+	return headings
+}
+
+func parseHeading(line string) (Heading, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return Heading{}, false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return Heading{}, false
+	}
+	text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(rest), "#"))
+	return Heading{Level: level, Text: text}, true
+}
+
+// outsideFences returns the lines of a markdown document that are not
+// inside fenced code blocks
+func outsideFences(content string) []string {
+	var lines []string
+	var fence string
+	for _, line := range SplitLines(content) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:3]
+			if fence == "" {
+				fence = marker
+			} else if fence == marker {
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// SplitLines splits content into lines, dropping the empty line after a
+// trailing newline. Extractors, sections, outlines and chunks all number
+// lines by it.
+func SplitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Notebook extracts Jupyter notebooks. Markdown cells are kept as they
+// are and each code cell becomes a section holding its source; outputs
+// are left out.
+type Notebook struct{}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string         `json:"cell_type"`
+	Source   notebookSource `json:"source"`
+}
+
+// notebookSource is a cell's source, stored either as one string or as a
+// list of lines
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = notebookSource(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*s = notebookSource(text)
+	return nil
+}
+
+// Extract titles the notebook by its first "# " heading. A code cell's
+// section sits one level below the markdown heading before it.
+func (Notebook) Extract(path string, content []byte) (*Document, error) {
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernelspec.Language
+	}
+
+	var w writer
+	var title string
+	level := 1 // level of the last markdown heading
+	code := 0
+	for _, cell := range nb.Cells {
+		source := strings.TrimRight(string(cell.Source), "\n")
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+
+		switch cell.CellType {
+		case "markdown":
+			for _, h := range ParseHeadings(source) {
+				if h.Level == 1 && title == "" {
+					title = h.Text
+				}
+				level = h.Level
+			}
+			if len(w.lines) > 0 {
+				w.line("")
+			}
+			w.lines = append(w.lines, strings.Split(source, "\n")...)
+			w.line("")
+		case "code":
+			code++
+			w.heading(level+1, fmt.Sprintf("Code cell %d", code))
+			w.code(lang, source)
+		case "raw":
+			w.lines = append(w.lines, strings.Split(source, "\n")...)
+			w.line("")
+		}
+	}
+
+	// Code cell sections are never the title
+	if title == "" {
+		title = baseName(path)
+	}
+	// Markdown cells bring their own headings and links
+	text := w.document(title, path)
+	doc, err := Markdown{}.Extract(path, []byte(text.Text))
+	if err != nil {
+		return nil, err
+	}
+	doc.Title = text.Title
+	return doc, nil
+}
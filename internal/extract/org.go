@@ -0,0 +1,70 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Org extracts Org-mode documents
+type Org struct{}
+
+var (
+	orgHeadingRe = regexp.MustCompile(`^(\*+)\s+(.*)$`)
+	orgKeywordRe = regexp.MustCompile(`^(TODO|DONE|NEXT|WAITING|CANCELLED)\s+`)
+	orgPriorRe   = regexp.MustCompile(`^\[#[A-Z]\]\s*`)
+	orgTagsRe    = regexp.MustCompile(`\s+:[\w@#%:]+:\s*$`)
+	orgLinkRe    = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]*)\])?\]`)
+	orgDrawerRe  = regexp.MustCompile(`^:[A-Za-z_-]+:$`)
+)
+
+// Extract converts "*" headings, uses #+TITLE as the title, keeps the
+// text of links and drops keywords, block markers, comments and drawers
+func (Org) Extract(path string, content []byte) (*Document, error) {
+	var w writer
+	var title string
+	drawer := false
+
+	for _, line := range SplitLines(string(content)) {
+		trimmed := strings.TrimSpace(line)
+		upper := strings.ToUpper(trimmed)
+
+		switch {
+		case drawer:
+			if upper == ":END:" {
+				drawer = false
+			}
+			continue
+		case orgDrawerRe.MatchString(trimmed) && upper != ":END:":
+			drawer = true
+			continue
+		case strings.HasPrefix(upper, "#+TITLE:"):
+			title = strings.TrimSpace(trimmed[len("#+TITLE:"):])
+			continue
+		case strings.HasPrefix(trimmed, "#+"), trimmed == "#", strings.HasPrefix(trimmed, "# "):
+			// Keywords, block markers and comments
+			continue
+		}
+
+		if m := orgHeadingRe.FindStringSubmatch(line); m != nil {
+			text := orgKeywordRe.ReplaceAllString(m[2], "")
+			text = orgPriorRe.ReplaceAllString(text, "")
+			text = orgTagsRe.ReplaceAllString(text, "")
+			w.heading(len(m[1]), w.orgLinks(text))
+			continue
+		}
+		w.line(w.orgLinks(line))
+	}
+	return w.document(title, path), nil
+}
+
+// orgLinks replaces [[target][text]] links with their text and records them
+func (w *writer) orgLinks(line string) string {
+	return orgLinkRe.ReplaceAllStringFunc(line, func(s string) string {
+		m := orgLinkRe.FindStringSubmatch(s)
+		w.link(m[2], m[1])
+		if m[2] != "" {
+			return m[2]
+		}
+		return m[1]
+	})
+}
@@ -0,0 +1,185 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Python extracts the docstrings of Python source files: the module
+// docstring, then one section per documented class or function with its
+// signature. Sections nest by indentation, so methods sit under their
+// class.
+type Python struct{}
+
+var (
+	pyDefRe    = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+(\w+)`)
+	pyStringRe = regexp.MustCompile(`^(?i:[rub]|br|rb)?("""|'''|"|')`)
+)
+
+// Extract titles the file by the first line of its module docstring, or
+// by the module name
+func (Python) Extract(path string, content []byte) (*Document, error) {
+	lines := SplitLines(strings.ReplaceAll(string(content), "\r\n", "\n"))
+	var w writer
+	var title string
+
+	// Module docstring: the first statement, after comments and blank lines
+	i := 0
+	for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(strings.TrimSpace(lines[i]), "#")) {
+		i++
+	}
+	if doc, next, ok := docstring(lines, i); ok {
+		title, _, _ = strings.Cut(doc, "\n")
+		writeDocstring(&w, doc)
+		i = next
+	}
+
+	// Enclosing definitions, by indentation
+	type scope struct {
+		indent int
+		name   string
+	}
+	var stack []scope
+
+	for ; i < len(lines); i++ {
+		m := pyDefRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		// The signature runs to the colon closing the header
+		end := i
+		depth := 0
+		for ; end < len(lines); end++ {
+			depth += strings.Count(lines[end], "(") + strings.Count(lines[end], "[") -
+				strings.Count(lines[end], ")") - strings.Count(lines[end], "]")
+			if depth <= 0 && strings.HasSuffix(strings.TrimSpace(stripComment(lines[end])), ":") {
+				break
+			}
+		}
+		if end == len(lines) {
+			end = i
+		}
+
+		name := m[3]
+		if len(stack) > 0 {
+			name = stack[len(stack)-1].name + "." + name
+		}
+		stack = append(stack, scope{indent: indent, name: name})
+
+		doc, _, ok := docstring(lines, end+1)
+		if !ok {
+			continue
+		}
+		w.heading(len(stack)+1, m[2]+" "+name)
+		w.code("python", dedent(lines[i:end+1], indent))
+		writeDocstring(&w, doc)
+	}
+
+	if title == "" {
+		title = "module " + baseName(path)
+	}
+	return w.document(title, path), nil
+}
+
+// docstring reads a string literal statement starting at or after line
+// i, returning its text and the line after it
+func docstring(lines []string, i int) (string, int, bool) {
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i >= len(lines) {
+		return "", i, false
+	}
+	first := strings.TrimSpace(lines[i])
+	m := pyStringRe.FindStringSubmatch(first)
+	if m == nil {
+		return "", i, false
+	}
+	quote := m[1]
+	rest := first[len(m[0]):]
+
+	// Single-line string
+	if idx := strings.Index(rest, quote); idx >= 0 {
+		return strings.TrimSpace(rest[:idx]), i + 1, true
+	}
+	if len(quote) == 1 {
+		return "", i, false
+	}
+
+	body := []string{rest}
+	for j := i + 1; j < len(lines); j++ {
+		if idx := strings.Index(lines[j], quote); idx >= 0 {
+			body = append(body, lines[j][:idx])
+			return cleanDocstring(body), j + 1, true
+		}
+		body = append(body, lines[j])
+	}
+	return "", i, false
+}
+
+// cleanDocstring removes the common indentation of a docstring's lines
+// after the first, as inspect.cleandoc does
+func cleanDocstring(lines []string) string {
+	indent := -1
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := []string{strings.TrimSpace(lines[0])}
+	for _, line := range lines[1:] {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n ")
+}
+
+func writeDocstring(w *writer, doc string) {
+	for _, line := range strings.Split(doc, "\n") {
+		w.line(line)
+	}
+	w.line("")
+}
+
+// dedent removes indent leading columns from each line
+func dedent(lines []string, indent int) string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && strings.TrimSpace(line[:indent]) == "" {
+			line = line[indent:]
+		}
+		out[i] = line
+	}
+	return strings.Join(out, "\n")
+}
+
+// stripComment drops a trailing # comment, ignoring # inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
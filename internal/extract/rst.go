@@ -0,0 +1,113 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RST extracts reStructuredText documents
+type RST struct{}
+
+var (
+	rstTargetRe = regexp.MustCompile(`^\.\.\s+_([^:]+):\s*(\S+)\s*$`)
+	rstLinkRe   = regexp.MustCompile("`([^`<]*?)\\s*<([^>`]+)>`__?")
+	rstRoleRe   = regexp.MustCompile(":[\\w-]+:`([^`<]*?)(?:\\s*<[^>`]+>)?`")
+	rstLiteral  = regexp.MustCompile("``([^`]+)``")
+)
+
+// rstAdornment reports whether a line is a section underline or overline:
+// one punctuation character repeated
+func rstAdornment(line string) (byte, bool) {
+	line = strings.TrimRight(line, " \t")
+	if len(line) < 2 || !strings.ContainsRune("=-`:'\"~^_*+#<>.", rune(line[0])) {
+		return 0, false
+	}
+	if strings.Trim(line, line[:1]) != "" {
+		return 0, false
+	}
+	return line[0], true
+}
+
+// Extract converts underlined (and overlined) section titles, ranking
+// styles in order of first use, keeps the text of links and roles and
+// drops comments, directive markers and link targets
+func (RST) Extract(path string, content []byte) (*Document, error) {
+	var w writer
+	lines := SplitLines(string(content))
+	// Section styles in the order they first appear; the index is the level
+	var styles []string
+	level := func(char byte, over bool) int {
+		style := string(char)
+		if over {
+			style += "/"
+		}
+		for i, s := range styles {
+			if s == style {
+				return i + 1
+			}
+		}
+		styles = append(styles, style)
+		return len(styles)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Overlined title: adornment, text, same adornment
+		if c, ok := rstAdornment(line); ok && i+2 < len(lines) {
+			text := strings.TrimSpace(lines[i+1])
+			if c2, ok := rstAdornment(lines[i+2]); ok && c2 == c && text != "" {
+				w.heading(level(c, true), w.rstInline(text))
+				i += 2
+				continue
+			}
+		}
+		// Underlined title: text, adornment at least as long
+		if trimmed != "" && line[0] != ' ' && i+1 < len(lines) {
+			if c, ok := rstAdornment(lines[i+1]); ok {
+				if _, isAdornment := rstAdornment(line); !isAdornment &&
+					len(strings.TrimSpace(lines[i+1])) >= utf8.RuneCountInString(trimmed) {
+					w.heading(level(c, false), w.rstInline(trimmed))
+					i++
+					continue
+				}
+			}
+		}
+		// Transitions
+		if _, ok := rstAdornment(line); ok && len(trimmed) >= 4 {
+			w.line("")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "..") && (len(trimmed) == 2 || trimmed[2] == ' ') {
+			if m := rstTargetRe.FindStringSubmatch(trimmed); m != nil {
+				w.link(m[1], m[2])
+				continue
+			}
+			// A directive keeps its argument, e.g. ".. note:: text";
+			// a comment is dropped
+			if _, arg, ok := strings.Cut(trimmed, "::"); ok {
+				w.line(w.rstInline(strings.TrimSpace(arg)))
+			}
+			continue
+		}
+		w.line(w.rstInline(line))
+	}
+	return w.document("", path), nil
+}
+
+// rstInline replaces links, roles and literals with their text
+func (w *writer) rstInline(line string) string {
+	line = rstLinkRe.ReplaceAllStringFunc(line, func(s string) string {
+		m := rstLinkRe.FindStringSubmatch(s)
+		w.link(m[1], m[2])
+		if m[1] != "" {
+			return m[1]
+		}
+		return m[2]
+	})
+	line = rstRoleRe.ReplaceAllString(line, "$1")
+	return rstLiteral.ReplaceAllString(line, "$1")
+}
@@ -0,0 +1,31 @@
+package extract
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTextTitle is the longest first line a plain text file is titled by
+const maxTextTitle = 100
+
+// Text indexes plain text as is
+type Text struct{}
+
+// Extract returns the content unchanged, titled by its first line when
+// that is short enough to be a title
+func (Text) Extract(path string, content []byte) (*Document, error) {
+	text := string(content)
+	doc := &Document{Title: baseName(path), Text: text, Links: bareURLs(text)}
+
+	for _, line := range SplitLines(text) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) <= maxTextTitle {
+			doc.Title = line
+		}
+		break
+	}
+	return doc, nil
+}
@@ -2,54 +2,70 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/NOTAschool/gqmd/internal/embed"
 	"github.com/NOTAschool/gqmd/internal/store"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-func statusHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	db, err := store.Open()
+// handlers serves tool calls against one index, opened per call
+type handlers struct {
+	dbPath string
+}
+
+func (h *handlers) open() (*store.Store, error) {
+	return store.OpenPath(h.dbPath)
+}
+
+func (h *handlers) statusHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	db, err := h.open()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
 	}
 	defer db.Close()
 
-	status, err := db.GetStatus()
+	model := embed.NewClientFromEnv(req.GetString("model", "")).Model()
+	status, err := db.GetStatusWithOptions(store.StatusOptions{Model: model})
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get status: %v", err)), nil
 	}
 
-	text := fmt.Sprintf(`gqmd Index Status:
-  Database: %s
-  Total documents: %d
-  Collections: %d
-  Has vector index: %v`,
-		status.DBPath,
-		status.TotalDocs,
-		status.Collections,
-		status.HasVectorIndex,
-	)
-
-	return mcp.NewToolResultText(text), nil
+	if req.GetBool("json", false) {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+	return mcp.NewToolResultText(status.String()), nil
 }
 
-func searchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (h *handlers) searchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := req.GetString("query", "")
 	if query == "" {
 		return mcp.NewToolResultError("query is required"), nil
 	}
 
-	limit := req.GetInt("limit", 10)
+	opts := store.SearchOptions{
+		Limit:    req.GetInt("limit", 10),
+		Offset:   req.GetInt("offset", 0),
+		Cursor:   req.GetString("cursor", ""),
+		MinScore: req.GetFloat("min_score", 0),
+		Explain:  req.GetBool("explain", false),
+	}
 
-	db, err := store.Open()
+	db, err := h.open()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
 	}
 	defer db.Close()
 
-	results, err := db.Search(query, limit)
+	results, page, err := db.SearchWithOptions(query, opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 	}
@@ -60,92 +76,203 @@ func searchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 
 	var text string
 	for i, r := range results {
-		text += fmt.Sprintf("%d. %s/%s\n   Title: %s\n   %s\n\n",
-			i+1, r.Collection, r.Path, r.Title, r.Snippet)
+		text += fmt.Sprintf("%d. %s/%s %s (%.3f)\n   Title: %s\n", i+1, r.Collection, r.Path, r.DocID, r.Score, r.Title)
+		if r.Context != "" {
+			text += fmt.Sprintf("   Context: %s\n", r.Context)
+		}
+		if r.Explanation != nil {
+			text += fmt.Sprintf("   Explain: %s\n", r.Explanation)
+		}
+		text += fmt.Sprintf("   %s\n\n", r.Snippet)
+	}
+	text += pageText(page)
+
+	return mcp.NewToolResultText(text), nil
+}
+
+func (h *handlers) getHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	collection := req.GetString("collection", "")
+	path := req.GetString("path", "")
+
+	if path == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+
+	opts := store.SliceOptions{
+		MaxLines: req.GetInt("max_lines", 0),
+		Section:  req.GetString("section", ""),
+	}
+	lineNumbers := req.GetBool("line_numbers", false)
+
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	doc, content, line, err := store.GetWithLine(path, func(path string) (*store.Document, string, error) {
+		return getDocument(db, collection, path)
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("document not found: %v", err)), nil
+	}
+	opts.FromLine = req.GetInt("from_line", line)
+
+	sl, err := store.SliceContent(content, opts)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	body := sl.Content
+	if lineNumbers {
+		body = sl.Numbered()
+	}
+
+	text := fmt.Sprintf("# %s\n\nPath: %s/%s\nDocID: %s\nModified: %s\n", doc.Title, doc.Collection, doc.Path, doc.DocID, doc.ModifiedAt)
+	if doc.Context != "" {
+		text += fmt.Sprintf("Context: %s\n", doc.Context)
+	}
+	if !opts.IsZero() {
+		text += fmt.Sprintf("Lines: %d-%d of %d\n", sl.FromLine, sl.ToLine, sl.TotalLines)
 	}
+	text += "\n---\n\n" + body
 
 	return mcp.NewToolResultText(text), nil
 }
 
-func getHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// chunkLocation describes where a vector hit sits in its document
+func chunkLocation(r store.VectorResult) string {
+	loc := fmt.Sprintf("Lines %d-%d", r.FromLine, r.ToLine)
+	if r.Breadcrumb != "" {
+		loc += " under " + r.Breadcrumb
+	}
+	return loc
+}
+
+// pageText tells the agent whether to ask for another page
+func pageText(page *store.Page) string {
+	if !page.HasMore {
+		return "has_more: false\n"
+	}
+	return fmt.Sprintf("has_more: true\ncursor: %s\n", page.NextCursor)
+}
+
+// getDocument resolves a document from an optional collection and a path,
+// which may also be a full collection/path or a #docid
+func getDocument(db *store.Store, collection, path string) (*store.Document, string, error) {
+	if collection == "" || store.IsDocID(path) {
+		return db.GetByRef(path)
+	}
+	return db.Get(collection, path)
+}
+
+func (h *handlers) outlineHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	collection := req.GetString("collection", "")
 	path := req.GetString("path", "")
 
-	if collection == "" || path == "" {
-		return mcp.NewToolResultError("collection and path are required"), nil
+	if path == "" {
+		return mcp.NewToolResultError("path is required"), nil
 	}
 
-	db, err := store.Open()
+	db, err := h.open()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
 	}
 	defer db.Close()
 
-	doc, content, err := db.Get(collection, path)
+	doc, content, err := getDocument(db, collection, path)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("document not found: %v", err)), nil
 	}
 
-	text := fmt.Sprintf("# %s\n\nPath: %s/%s\nModified: %s\n\n---\n\n%s",
-		doc.Title, doc.Collection, doc.Path, doc.ModifiedAt, content)
+	entries := store.BuildOutline(content)
+
+	if len(entries) == 0 {
+		return mcp.NewToolResultText("No headings found"), nil
+	}
+
+	text := fmt.Sprintf("# %s\n\nPath: %s/%s\n\n", doc.Title, doc.Collection, doc.Path)
+	for _, e := range entries {
+		text += fmt.Sprintf("%s%s %s (lines %d-%d, %d bytes)\n",
+			strings.Repeat("  ", e.Level-1), strings.Repeat("#", e.Level), e.Text, e.Line, e.EndLine, e.Bytes)
+	}
 
 	return mcp.NewToolResultText(text), nil
 }
 
-func multiGetHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (h *handlers) multiGetHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	paths := req.GetStringSlice("paths", nil)
+	if pattern := req.GetString("pattern", ""); pattern != "" {
+		paths = append(paths, store.SplitRefs(pattern)...)
+	}
 	if len(paths) == 0 {
-		return mcp.NewToolResultError("paths array is required"), nil
+		return mcp.NewToolResultError("paths or pattern is required"), nil
 	}
 
 	maxBytes := req.GetInt("max_bytes", 10*1024)
+	maxLines := req.GetInt("max_lines", 0)
 
-	db, err := store.Open()
+	db, err := h.open()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
 	}
 	defer db.Close()
 
-	results, err := db.MultiGet(paths, maxBytes)
+	resp, err := db.MultiGet(paths, maxBytes, maxLines)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("multi_get failed: %v", err)), nil
 	}
 
-	if len(results) == 0 {
-		return mcp.NewToolResultText("No documents found"), nil
-	}
-
 	var text string
-	for _, r := range results {
-		text += fmt.Sprintf("## %s\n\nPath: %s/%s\n\n%s\n\n---\n\n",
-			r.Document.Title, r.Document.Collection, r.Document.Path, r.Content)
+	for _, r := range resp.Results {
+		text += fmt.Sprintf("## %s\n\nPath: %s/%s\nDocID: %s\n\n%s\n\n---\n\n",
+			r.Document.Title, r.Document.Collection, r.Document.Path, r.Document.DocID, r.Content)
+	}
+	if len(resp.Results) == 0 {
+		text = "No documents found\n"
+	}
+	if len(resp.Missing) > 0 {
+		text += fmt.Sprintf("\nMissing: %s\n", strings.Join(resp.Missing, ", "))
+	}
+	if len(resp.Skipped) > 0 {
+		text += fmt.Sprintf("\nSkipped (max_bytes reached): %s\n", strings.Join(resp.Skipped, ", "))
 	}
 
 	return mcp.NewToolResultText(text), nil
 }
 
-func vectorSearchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (h *handlers) vectorSearchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := req.GetString("query", "")
 	if query == "" {
 		return mcp.NewToolResultError("query is required"), nil
 	}
 
-	limit := req.GetInt("limit", 10)
+	opts := store.SearchOptions{
+		Limit:    req.GetInt("limit", 10),
+		Offset:   req.GetInt("offset", 0),
+		Cursor:   req.GetString("cursor", ""),
+		MinScore: req.GetFloat("min_score", 0),
+		Explain:  req.GetBool("explain", false),
+
+		SnippetLength: req.GetInt("snippet_length", store.DefaultSnippetLength),
+		Collapse:      req.GetBool("collapse", false),
+	}
 
 	// Get embedding from Ollama
-	embedClient := embed.NewClient("", "")
+	embedClient := embed.NewClientFromEnv("")
 	queryVec, err := embedClient.Embed(query)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("embedding failed: %v", err)), nil
 	}
+	opts.Model = embedClient.Model()
 
-	db, err := store.Open()
+	db, err := h.open()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
 	}
 	defer db.Close()
 
-	results, err := db.VectorSearch(store.Vector(queryVec), limit)
+	results, page, err := db.VectorSearchWithOptions(store.Vector(queryVec), opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("vector search failed: %v", err)), nil
 	}
@@ -156,9 +283,171 @@ func vectorSearchHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.Cal
 
 	var text string
 	for i, r := range results {
-		text += fmt.Sprintf("%d. %s/%s (%.3f)\n   %s\n\n",
-			i+1, r.Collection, r.Path, r.Score, r.Title)
+		text += fmt.Sprintf("%d. %s/%s %s (%.3f)\n   %s\n", i+1, r.Collection, r.Path, r.DocID, r.Score, r.Title)
+		if r.Context != "" {
+			text += fmt.Sprintf("   Context: %s\n", r.Context)
+		}
+		if r.Explanation != nil {
+			text += fmt.Sprintf("   Explain: %s\n", r.Explanation)
+		}
+		if r.Text != "" {
+			text += fmt.Sprintf("   %s\n   %s\n", chunkLocation(r), r.Text)
+		}
+		text += "\n"
 	}
+	text += pageText(page)
 
 	return mcp.NewToolResultText(text), nil
 }
+
+func (h *handlers) listCollectionsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	cols, err := db.ListCollections()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("list collections failed: %v", err)), nil
+	}
+
+	if len(cols) == 0 {
+		return mcp.NewToolResultText("No collections"), nil
+	}
+
+	var text string
+	for _, c := range cols {
+		text += fmt.Sprintf("%s -> %s (%s)\n", c.Name, c.Path, c.Pattern)
+	}
+
+	return mcp.NewToolResultText(text), nil
+}
+
+func (h *handlers) addCollectionHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := req.GetString("name", "")
+	path := req.GetString("path", "")
+	pattern := req.GetString("pattern", "**/*.md")
+
+	if name == "" || path == "" {
+		return mcp.NewToolResultError("name and path are required"), nil
+	}
+	var maxSize int64
+	if v := req.GetString("max_size", ""); v != "" {
+		var err error
+		if maxSize, err = store.ParseMaxFileSize(v); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid path: %v", err)), nil
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("path not found: %v", err)), nil
+	}
+	if !info.IsDir() {
+		return mcp.NewToolResultError("path must be a directory"), nil
+	}
+
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	if err := db.AddCollection(name, absPath, pattern); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add collection: %v", err)), nil
+	}
+	if maxSize != 0 {
+		if err := db.SetMaxFileSize(name, maxSize); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set size limit: %v", err)), nil
+		}
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Added collection %q -> %s", name, absPath)), nil
+}
+
+func (h *handlers) removeCollectionHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := req.GetString("name", "")
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	if err := db.RemoveCollection(name); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to remove collection: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Removed collection %q", name)), nil
+}
+
+func (h *handlers) scanHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	names := []string{req.GetString("name", "")}
+	if names[0] == "" {
+		cols, err := db.ListCollections()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("list collections failed: %v", err)), nil
+		}
+		names = names[:0]
+		for _, c := range cols {
+			names = append(names, c.Name)
+		}
+	}
+
+	prog := progressReporter(ctx, req)
+	var text string
+	for _, name := range names {
+		result, err := db.ScanCollectionWithOptions(name, store.ScanOptions{Progress: prog.Func()})
+		if err != nil {
+			text += fmt.Sprintf("%s: error: %v\n", name, err)
+			continue
+		}
+		text += fmt.Sprintf("%s: %s\n", name, result)
+		for _, e := range result.Errors {
+			text += fmt.Sprintf("  error: %s\n", &e)
+		}
+		for _, f := range result.Skipped {
+			text += fmt.Sprintf("  %s: %s\n", f.Path, f.Reason)
+		}
+	}
+	if text == "" {
+		text = "No collections"
+	}
+
+	return mcp.NewToolResultText(text), nil
+}
+
+func (h *handlers) embedHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	db, err := h.open()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open database: %v", err)), nil
+	}
+	defer db.Close()
+
+	client := embed.NewClientFromEnv(req.GetString("model", ""))
+	prog := progressReporter(ctx, req)
+
+	result, err := db.EmbedDocuments(client.Model(), client.Embed, store.EmbedOptions{
+		Collection: req.GetString("name", ""),
+		Progress:   prog.Func(),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("embedding failed: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Embedded: %d, Errors: %d", result.Embedded, result.Errors)), nil
+}
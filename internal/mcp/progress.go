@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressInterval throttles progress notifications on large collections
+const progressInterval = 250 * time.Millisecond
+
+// progressReporter sends MCP progress notifications for the request when
+// the client sent a progress token. Progress from several operations in
+// one request is accumulated so it keeps increasing.
+func progressReporter(ctx context.Context, req mcp.CallToolRequest) *progress {
+	p := &progress{ctx: ctx}
+	if req.Params.Meta != nil && req.Params.Meta.ProgressToken != nil {
+		p.token = req.Params.Meta.ProgressToken
+		p.srv = server.ServerFromContext(ctx)
+	}
+	return p
+}
+
+type progress struct {
+	ctx   context.Context
+	srv   *server.MCPServer
+	token mcp.ProgressToken
+	base  int
+	done  int
+	last  time.Time
+}
+
+// Func returns the callback for the next operation, or nil when the
+// client did not ask for progress
+func (p *progress) Func() store.ProgressFunc {
+	if p.srv == nil {
+		return nil
+	}
+	p.base += p.done
+	p.done = 0
+	return p.send
+}
+
+func (p *progress) send(done, total int, message string) {
+	p.done = done
+	if done < total && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+
+	params := map[string]any{
+		"progressToken": p.token,
+		"progress":      p.base + done,
+	}
+	if total > 0 {
+		params["total"] = p.base + total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	_ = p.srv.SendNotificationToClient(p.ctx, "notifications/progress", params)
+}
@@ -1,21 +1,106 @@
 package mcp
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os"
 
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/NOTAschool/gqmd/internal/watch"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/mark3labs/mcp-go/util"
 )
 
-func StartServer() error {
+// Options configures the MCP server
+type Options struct {
+	// AllowWrite exposes tools that modify the index
+	AllowWrite bool
+	// Watch, if set, keeps the index in sync with collection directories
+	Watch *watch.Options
+	// DBPath is the index database, the default index if empty
+	DBPath string
+}
+
+func newServer(opts Options) (*server.MCPServer, error) {
 	s := server.NewMCPServer(
 		"gqmd",
 		"0.1.0",
 		server.WithToolCapabilities(true),
 	)
 
-	if err := registerTools(s); err != nil {
-		return fmt.Errorf("failed to register tools: %w", err)
+	if err := registerTools(s, opts); err != nil {
+		return nil, fmt.Errorf("failed to register tools: %w", err)
+	}
+	return s, nil
+}
+
+// resolveDBPath fills in the default index when no database is given
+func (o *Options) resolveDBPath() error {
+	if o.DBPath != "" {
+		return nil
+	}
+	var err error
+	o.DBPath, err = store.ResolvePath("", "")
+	return err
+}
+
+// StartServer serves MCP over stdio
+func StartServer(opts Options) error {
+	if err := opts.resolveDBPath(); err != nil {
+		return err
+	}
+	s, err := newServer(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Watch != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := startWatcher(ctx, opts.DBPath, *opts.Watch); err != nil {
+			return err
+		}
 	}
 
 	return server.ServeStdio(s)
 }
+
+// NewHTTPHandler returns an http.Handler serving MCP over the streamable
+// HTTP transport. The watcher option is ignored; the caller owns the index.
+func NewHTTPHandler(opts Options, logger util.Logger) (http.Handler, error) {
+	if err := opts.resolveDBPath(); err != nil {
+		return nil, err
+	}
+	s, err := newServer(opts)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewStreamableHTTPServer(s, server.WithLogger(logger)), nil
+}
+
+// startWatcher runs a watcher in the background, logging to stderr since
+// stdout carries the MCP protocol
+func startWatcher(ctx context.Context, dbPath string, opts watch.Options) error {
+	db, err := store.OpenPath(dbPath)
+	if err != nil {
+		return err
+	}
+
+	opts.Logf = func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "gqmd watch: "+format+"\n", args...)
+	}
+	w, err := watch.New(db, opts)
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	go func() {
+		defer db.Close()
+		if err := w.Run(ctx); err != nil {
+			opts.Logf("%v", err)
+		}
+	}()
+	return nil
+}
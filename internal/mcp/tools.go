@@ -5,44 +5,125 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerTools(s *server.MCPServer) error {
+func registerTools(s *server.MCPServer, opts Options) error {
+	h := &handlers{dbPath: opts.DBPath}
+
 	// status tool
 	statusTool := mcp.NewTool("status",
-		mcp.WithDescription("Show index status and health information"),
+		mcp.WithDescription("Show index status: per-collection document counts and sizes, last scans, embedding coverage per model and database size"),
+		mcp.WithString("model", mcp.Description("Count documents needing embedding for this model (default: the configured model)")),
+		mcp.WithBoolean("json", mcp.Description("Return the full status as JSON")),
 	)
-	s.AddTool(statusTool, statusHandler)
+	s.AddTool(statusTool, h.statusHandler)
 
 	// search tool
 	searchTool := mcp.NewTool("search",
 		mcp.WithDescription("Search documents using FTS5 full-text search"),
 		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
 		mcp.WithNumber("limit", mcp.Description("Max results (default 10)")),
+		mcp.WithNumber("offset", mcp.Description("Number of results to skip")),
+		mcp.WithString("cursor", mcp.Description("Cursor from a previous response to fetch the next page")),
+		mcp.WithNumber("min_score", mcp.Description("Minimum normalized score from 0 to 1")),
+		mcp.WithBoolean("explain", mcp.Description("Show per-column BM25 scores and rank for each hit")),
 	)
-	s.AddTool(searchTool, searchHandler)
+	s.AddTool(searchTool, h.searchHandler)
 
 	// get tool
 	getTool := mcp.NewTool("get",
-		mcp.WithDescription("Get a document by collection and path"),
-		mcp.WithString("collection", mcp.Required(), mcp.Description("Collection name")),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Document path")),
+		mcp.WithDescription("Get a document by collection and path or #docid, optionally only a line range or heading section"),
+		mcp.WithString("collection", mcp.Description("Collection name (omit when path is collection/path or #docid)")),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Document path or #docid, optionally with a :line suffix")),
+		mcp.WithNumber("from_line", mcp.Description("First line to return (1-based)")),
+		mcp.WithNumber("max_lines", mcp.Description("Max lines to return")),
+		mcp.WithString("section", mcp.Description("Return only the content under this heading")),
+		mcp.WithBoolean("line_numbers", mcp.Description("Prefix each line with its line number")),
+	)
+	s.AddTool(getTool, h.getHandler)
+
+	// outline tool
+	outlineTool := mcp.NewTool("outline",
+		mcp.WithDescription("Show the heading tree of a document with line numbers and section sizes"),
+		mcp.WithString("collection", mcp.Description("Collection name (omit when path is collection/path or #docid)")),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Document path or #docid")),
 	)
-	s.AddTool(getTool, getHandler)
+	s.AddTool(outlineTool, h.outlineHandler)
 
 	// multi_get tool
 	multiGetTool := mcp.NewTool("multi_get",
-		mcp.WithDescription("Get multiple documents by paths"),
-		mcp.WithArray("paths", mcp.Required(), mcp.Description("Array of collection/path strings")),
+		mcp.WithDescription("Get multiple documents by paths, docids or glob patterns"),
+		mcp.WithArray("paths", mcp.Description("Array of collection/path, #docid or glob strings"), mcp.WithStringItems()),
+		mcp.WithString("pattern", mcp.Description("Glob pattern such as notes/2026/**/*.md, or a comma-separated list (\\, for a comma in a path)")),
 		mcp.WithNumber("max_bytes", mcp.Description("Max total bytes (default 10KB)")),
+		mcp.WithNumber("max_lines", mcp.Description("Max lines per document")),
 	)
-	s.AddTool(multiGetTool, multiGetHandler)
+	s.AddTool(multiGetTool, h.multiGetHandler)
 
 	// vector_search tool
 	vectorSearchTool := mcp.NewTool("vector_search",
 		mcp.WithDescription("Semantic search using vector embeddings (requires Ollama)"),
 		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
 		mcp.WithNumber("limit", mcp.Description("Max results (default 10)")),
+		mcp.WithNumber("offset", mcp.Description("Number of results to skip")),
+		mcp.WithString("cursor", mcp.Description("Cursor from a previous response to fetch the next page")),
+		mcp.WithNumber("min_score", mcp.Description("Minimum cosine similarity from 0 to 1")),
+		mcp.WithBoolean("explain", mcp.Description("Show similarity and rank for each hit")),
+		mcp.WithNumber("snippet_length", mcp.Description("Bytes of matching chunk text per hit (default 300, -1 for all)")),
+		mcp.WithBoolean("collapse", mcp.Description("Return only the best matching chunk of each document")),
+	)
+	s.AddTool(vectorSearchTool, h.vectorSearchHandler)
+
+	// list_collections tool
+	listCollectionsTool := mcp.NewTool("list_collections",
+		mcp.WithDescription("List registered collections"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	s.AddTool(listCollectionsTool, h.listCollectionsHandler)
+
+	if !opts.AllowWrite {
+		return nil
+	}
+
+	// add_collection tool
+	addCollectionTool := mcp.NewTool("add_collection",
+		mcp.WithDescription("Register a directory as a new collection"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Collection name")),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Directory path")),
+		mcp.WithString("pattern", mcp.Description("Glob pattern for files (default **/*.md); **/*.{md,org,rst} includes several formats")),
+		mcp.WithString("max_size", mcp.Description("Skip files larger than this, e.g. 50MB (default 10MiB, none for no limit)")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+	)
+	s.AddTool(addCollectionTool, h.addCollectionHandler)
+
+	// remove_collection tool
+	removeCollectionTool := mcp.NewTool("remove_collection",
+		mcp.WithDescription("Remove a collection and all its indexed documents"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Collection name")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	)
+	s.AddTool(removeCollectionTool, h.removeCollectionHandler)
+
+	// scan tool
+	scanTool := mcp.NewTool("scan",
+		mcp.WithDescription("Scan and index a collection, or all collections"),
+		mcp.WithString("name", mcp.Description("Collection name (default all)")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	s.AddTool(scanTool, h.scanHandler)
+
+	// embed tool
+	embedTool := mcp.NewTool("embed",
+		mcp.WithDescription("Generate vector embeddings for documents that have none yet (requires Ollama)"),
+		mcp.WithString("name", mcp.Description("Collection name (default all)")),
+		mcp.WithString("model", mcp.Description("Embedding model (default nomic-embed-text)")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
 	)
-	s.AddTool(vectorSearchTool, vectorSearchHandler)
+	s.AddTool(embedTool, h.embedHandler)
 
 	return nil
 }
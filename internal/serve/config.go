@@ -0,0 +1,116 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultListen is the address the MCP HTTP endpoint listens on
+	DefaultListen = "127.0.0.1:8765"
+	// DefaultInterval is how often a collection is rescanned
+	DefaultInterval = time.Hour
+)
+
+// Config is the daemon configuration, read from a JSON file. A missing
+// file means all defaults.
+type Config struct {
+	Listen     string `json:"listen"`
+	AllowWrite bool   `json:"allow_write"`
+	// Interval, Embed and Model apply to every collection unless overridden
+	Interval    Duration            `json:"interval"`
+	Embed       bool                `json:"embed"`
+	Model       string              `json:"model"`
+	Collections map[string]Schedule `json:"collections"`
+}
+
+// Schedule overrides the defaults for one collection
+type Schedule struct {
+	Interval Duration `json:"interval"`
+	Embed    *bool    `json:"embed"`
+	Disabled bool     `json:"disabled"`
+}
+
+// Duration is a time.Duration written as a string such as "30m" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// DefaultConfigPath returns $XDG_CONFIG_HOME/gqmd/serve.json
+func DefaultConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gqmd", "serve.json"), nil
+}
+
+// LoadConfig reads the config at path and fills in defaults
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	if cfg.Listen == "" {
+		cfg.Listen = DefaultListen
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = Duration(DefaultInterval)
+	}
+	return cfg, nil
+}
+
+// schedule returns how often to scan a collection and whether to embed
+// it afterwards. ok is false for disabled collections.
+func (c *Config) schedule(name string) (interval time.Duration, embed, ok bool) {
+	interval, embed = time.Duration(c.Interval), c.Embed
+	sc, found := c.Collections[name]
+	if !found {
+		return interval, embed, true
+	}
+	if sc.Disabled {
+		return 0, false, false
+	}
+	if sc.Interval > 0 {
+		interval = time.Duration(sc.Interval)
+	}
+	if sc.Embed != nil {
+		embed = *sc.Embed
+	}
+	return interval, embed, true
+}
@@ -0,0 +1,163 @@
+package serve
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/embed"
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+// maxIdle bounds how long the scheduler sleeps, so collections added
+// while it runs are picked up
+const maxIdle = time.Minute
+
+// Scheduler scans and embeds each collection on its own interval
+type Scheduler struct {
+	db  *store.Store
+	log *slog.Logger
+	// embedder returns the embedding function and resolved name for a model
+	embedder func(model string) (store.EmbedFunc, string)
+
+	mu      sync.Mutex
+	cfg     *Config
+	lastRun map[string]time.Time
+	wake    chan struct{}
+}
+
+// NewScheduler creates a scheduler for the collections in db
+func NewScheduler(db *store.Store, cfg *Config, log *slog.Logger) *Scheduler {
+	return &Scheduler{
+		db:  db,
+		log: log,
+		embedder: func(model string) (store.EmbedFunc, string) {
+			client := embed.NewClientFromEnv(model)
+			return client.Embed, client.Model()
+		},
+		cfg:     cfg,
+		lastRun: make(map[string]time.Time),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// SetConfig replaces the configuration. New intervals apply from the
+// last run of each collection.
+func (s *Scheduler) SetConfig(cfg *Config) {
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run scans collections as they fall due until ctx is cancelled. Every
+// collection is scanned once at startup.
+func (s *Scheduler) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		next, err := s.runDue(ctx, time.Now())
+		if err != nil {
+			s.log.Error("list collections", "error", err)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+	}
+}
+
+// runDue runs every collection that is due at now and returns how long
+// to wait before the next one is
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) (time.Duration, error) {
+	cols, err := s.db.ListCollections()
+	if err != nil {
+		return maxIdle, err
+	}
+
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	wait := maxIdle
+	for _, c := range cols {
+		if ctx.Err() != nil {
+			return 0, nil
+		}
+		interval, withEmbed, ok := cfg.schedule(c.Name)
+		if !ok {
+			continue
+		}
+		last, ran := s.lastRun[c.Name]
+		if !ran || !now.Before(last.Add(interval)) {
+			s.runCollection(c.Name, withEmbed, cfg.Model)
+			last = time.Now()
+			s.lastRun[c.Name] = last
+		}
+		if d := last.Add(interval).Sub(now); d < wait {
+			wait = max(d, 0)
+		}
+	}
+	return wait, nil
+}
+
+// runCollection scans a collection and optionally embeds new content
+func (s *Scheduler) runCollection(name string, withEmbed bool, model string) {
+	start := time.Now()
+	result, err := s.db.ScanCollection(name)
+	if err != nil {
+		s.log.Error("scan failed", "collection", name, "error", err)
+		return
+	}
+	level := slog.LevelInfo
+	if len(result.Errors) > 0 {
+		level = slog.LevelWarn
+	}
+	s.log.Log(context.Background(), level, "scan finished",
+		"collection", name,
+		"added", result.Added,
+		"updated", result.Updated,
+		"unchanged", result.Unchanged,
+		"removed", result.Removed,
+		"skipped", len(result.Skipped),
+		"errors", len(result.Errors),
+		"duration", time.Since(start),
+	)
+	for _, e := range result.Errors {
+		s.log.Warn("scan error", "collection", name, "path", e.Path, "stage", e.Stage, "error", e.Err)
+	}
+
+	if !withEmbed {
+		return
+	}
+	start = time.Now()
+	fn, resolved := s.embedder(model)
+	er, err := s.db.EmbedDocuments(resolved, fn, store.EmbedOptions{Collection: name})
+	if err != nil {
+		s.log.Error("embed failed", "collection", name, "model", resolved, "error", err)
+		return
+	}
+	s.log.Info("embed finished",
+		"collection", name,
+		"model", resolved,
+		"embedded", er.Embedded,
+		"errors", er.Errors,
+		"duration", time.Since(start),
+	)
+}
@@ -0,0 +1,134 @@
+// Package serve runs gqmd as a long-lived daemon: scheduled scans and
+// embedding per collection, plus MCP over HTTP.
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/mcp"
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+// shutdownTimeout bounds how long in-flight MCP requests may take on exit
+const shutdownTimeout = 10 * time.Second
+
+// Options configures Run
+type Options struct {
+	ConfigPath string
+	DBPath     string // the default index if empty
+	Logger     *slog.Logger
+}
+
+// Run serves until ctx is cancelled. SIGHUP reloads the config file;
+// schedule changes apply immediately, listen and allow_write need a restart.
+func Run(ctx context.Context, opts Options) error {
+	log := opts.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+
+	cfg, err := LoadConfig(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	if opts.DBPath == "" {
+		if opts.DBPath, err = store.ResolvePath("", ""); err != nil {
+			return err
+		}
+	}
+	db, err := store.OpenPath(opts.DBPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	handler, err := mcp.NewHTTPHandler(mcp.Options{AllowWrite: cfg.AllowWrite, DBPath: opts.DBPath}, mcpLogger{log})
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", handler)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sched := NewScheduler(db, cfg, log)
+	schedDone := make(chan struct{})
+	go func() {
+		defer close(schedDone)
+		sched.Run(ctx)
+	}()
+
+	log.Info("serving", "listen", ln.Addr().String(), "config", opts.ConfigPath, "db", opts.DBPath,
+		"allow_write", cfg.AllowWrite, "interval", time.Duration(cfg.Interval), "embed", cfg.Embed)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("shutting down")
+			shutdownCtx, stop := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer stop()
+			err := srv.Shutdown(shutdownCtx)
+			<-schedDone
+			return err
+
+		case err := <-serveErr:
+			cancel()
+			<-schedDone
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+
+		case <-hup:
+			next, err := LoadConfig(opts.ConfigPath)
+			if err != nil {
+				log.Error("reload config", "error", err)
+				continue
+			}
+			if next.Listen != cfg.Listen || next.AllowWrite != cfg.AllowWrite {
+				log.Warn("listen and allow_write changes take effect after a restart")
+			}
+			cfg = next
+			sched.SetConfig(cfg)
+			log.Info("config reloaded", "interval", time.Duration(cfg.Interval), "embed", cfg.Embed)
+		}
+	}
+}
+
+// mcpLogger routes the MCP transport's log lines into slog
+type mcpLogger struct {
+	log *slog.Logger
+}
+
+func (l mcpLogger) Infof(format string, v ...any) {
+	l.log.Info(fmt.Sprintf(format, v...), "component", "mcp")
+}
+
+func (l mcpLogger) Errorf(format string, v ...any) {
+	l.log.Error(fmt.Sprintf(format, v...), "component", "mcp")
+}
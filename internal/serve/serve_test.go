@@ -0,0 +1,170 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig missing: %v", err)
+	}
+	if cfg.Listen != DefaultListen || time.Duration(cfg.Interval) != DefaultInterval {
+		t.Errorf("defaults = %q %v", cfg.Listen, time.Duration(cfg.Interval))
+	}
+
+	path := filepath.Join(dir, "serve.json")
+	os.WriteFile(path, []byte(`{
+		"interval": "2h",
+		"embed": true,
+		"collections": {
+			"notes": {"interval": "10m"},
+			"raw": {"embed": false},
+			"archive": {"disabled": true}
+		}
+	}`), 0644)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		embed    bool
+		ok       bool
+	}{
+		{"other", 2 * time.Hour, true, true},
+		{"notes", 10 * time.Minute, true, true},
+		{"raw", 2 * time.Hour, false, true},
+		{"archive", 0, false, false},
+	}
+	for _, tt := range tests {
+		interval, embed, ok := cfg.schedule(tt.name)
+		if interval != tt.interval || embed != tt.embed || ok != tt.ok {
+			t.Errorf("schedule(%s) = %v %v %v, want %v %v %v",
+				tt.name, interval, embed, ok, tt.interval, tt.embed, tt.ok)
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"interval": 5}`), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for numeric interval")
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# A\n"), 0644)
+
+	db, err := store.OpenPath(filepath.Join(dir, "index.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath: %v", err)
+	}
+	defer db.Close()
+	if err := db.AddCollection("docs", docs, "**/*.md"); err != nil {
+		t.Fatalf("AddCollection: %v", err)
+	}
+	if err := db.AddCollection("off", docs, "**/*.md"); err != nil {
+		t.Fatalf("AddCollection: %v", err)
+	}
+
+	cfg := &Config{
+		Interval:    Duration(time.Hour),
+		Embed:       true,
+		Model:       "test",
+		Collections: map[string]Schedule{"off": {Disabled: true}},
+	}
+	s := NewScheduler(db, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	embedded := 0
+	s.embedder = func(model string) (store.EmbedFunc, string) {
+		return func(string) ([]float32, error) {
+			embedded++
+			return []float32{1, 0}, nil
+		}, model
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	wait, err := s.runDue(ctx, now)
+	if err != nil {
+		t.Fatalf("runDue: %v", err)
+	}
+	if _, _, err := db.Get("docs", "a.md"); err != nil {
+		t.Errorf("docs not scanned: %v", err)
+	}
+	if _, ran := s.lastRun["off"]; ran {
+		t.Error("disabled collection was scanned")
+	}
+	if embedded == 0 {
+		t.Error("expected embedding after scan")
+	}
+	if wait > maxIdle {
+		t.Errorf("wait = %v, want at most %v", wait, maxIdle)
+	}
+
+	// Not due again until the interval has passed
+	first := s.lastRun["docs"]
+	s.runDue(ctx, now.Add(30*time.Minute))
+	if !s.lastRun["docs"].Equal(first) {
+		t.Error("collection rescanned before its interval")
+	}
+
+	// A shorter interval from a reload makes it due
+	s.SetConfig(&Config{Interval: Duration(10 * time.Minute)})
+	s.runDue(ctx, now.Add(30*time.Minute))
+	if s.lastRun["docs"].Equal(first) {
+		t.Error("collection not rescanned after reload")
+	}
+}
+
+func TestSchedulerLogsScanCounters(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	os.MkdirAll(docs, 0755)
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		os.WriteFile(filepath.Join(docs, name), []byte("# "+name+"\n"), 0644)
+	}
+
+	db, err := store.OpenPath(filepath.Join(dir, "index.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath: %v", err)
+	}
+	defer db.Close()
+	db.AddCollection("docs", docs, "**/*.md")
+	if _, err := db.ScanCollection("docs"); err != nil {
+		t.Fatalf("ScanCollection: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# a.md\nedited\n"), 0644)
+	os.Remove(filepath.Join(docs, "b.md"))
+
+	var buf bytes.Buffer
+	s := NewScheduler(db, &Config{Interval: Duration(time.Hour)}, slog.New(slog.NewJSONHandler(&buf, nil)))
+	s.runCollection("docs", false, "")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line %q: %v", buf.String(), err)
+	}
+	want := map[string]float64{"added": 0, "updated": 1, "unchanged": 1, "removed": 1, "skipped": 0, "errors": 0}
+	for key, n := range want {
+		if entry[key] != n {
+			t.Errorf("logged %s = %v, want %v", key, entry[key], n)
+		}
+	}
+}
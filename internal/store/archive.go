@@ -0,0 +1,548 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	archiveFormat = "gqmd-archive"
+	// ArchiveVersion is the archive format version written by Export.
+	// Import reads this version and older.
+	ArchiveVersion = 1
+)
+
+// An archive is a gzipped JSON Lines stream: a header line, then one
+// record per collection, context, content blob, document, chunk and
+// embedding, in that order.
+
+type archiveHeader struct {
+	Format        string       `json:"format"`
+	Version       int          `json:"version"`
+	SchemaVersion int          `json:"schema_version"`
+	CreatedAt     string       `json:"created_at"`
+	Models        []ModelUsage `json:"models"`
+}
+
+type archiveRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ModelUsage names an embedding model and its vector size
+type ModelUsage struct {
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+}
+
+type archiveCollection struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Pattern     string `json:"pattern"`
+	CreatedAt   string `json:"created_at"`
+	MaxFileSize int64  `json:"max_file_size,omitempty"`
+}
+
+type archiveContext struct {
+	Collection string `json:"collection"`
+	Path       string `json:"path"`
+	Context    string `json:"context"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type archiveContent struct {
+	Hash      string `json:"hash"`
+	Doc       string `json:"doc"`
+	CreatedAt string `json:"created_at"`
+}
+
+type archiveDocument struct {
+	Collection string `json:"collection"`
+	Path       string `json:"path"`
+	Title      string `json:"title"`
+	Hash       string `json:"hash"`
+	CreatedAt  string `json:"created_at"`
+	ModifiedAt string `json:"modified_at"`
+	Active     bool   `json:"active"`
+}
+
+type archiveChunk struct {
+	Hash       string `json:"hash"`
+	ChunkIdx   int    `json:"chunk_idx"`
+	FromLine   int    `json:"from_line"`
+	ToLine     int    `json:"to_line"`
+	Breadcrumb string `json:"breadcrumb"`
+	Text       string `json:"text"`
+}
+
+type archiveEmbedding struct {
+	Hash       string `json:"hash"`
+	ChunkIdx   int    `json:"chunk_idx"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	Vector     []byte `json:"vector"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// ArchiveStats counts the records exported or imported
+type ArchiveStats struct {
+	Version int
+	Models  []ModelUsage
+	// SkippedModels are archived embedding models Import left out, as
+	// they are not the model in use
+	SkippedModels []ModelUsage
+	Collections   int
+	Contexts      int
+	Content       int
+	Documents     int
+	Chunks        int
+	Embeddings    int
+}
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	Collections []string // empty for all
+}
+
+// Export writes collections and everything indexed for them to w
+func (s *Store) Export(w io.Writer, opts ExportOptions) (*ArchiveStats, error) {
+	names := opts.Collections
+	if len(names) == 0 {
+		cols, err := s.ListCollections()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cols {
+			names = append(names, c.Name)
+		}
+	}
+	for _, name := range opts.Collections {
+		if _, err := s.GetCollection(name); err != nil {
+			return nil, fmt.Errorf("collection %q not found", name)
+		}
+	}
+
+	// Every query filters by the exported collections
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(names)), ",") + ")"
+	if len(names) == 0 {
+		in = "(NULL)"
+	}
+	args := make([]any, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	exportedHashes := `SELECT hash FROM documents WHERE collection IN ` + in
+
+	stats := &ArchiveStats{Version: ArchiveVersion}
+	models, err := s.embeddingModels(`WHERE hash IN (`+exportedHashes+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	stats.Models = models
+	schema, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	header := archiveHeader{Format: archiveFormat, Version: ArchiveVersion, SchemaVersion: schema, CreatedAt: nowISO(), Models: models}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+
+	write := func(typ string, count *int) func(v any) error {
+		return func(v any) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			*count++
+			return enc.Encode(archiveRecord{Type: typ, Data: data})
+		}
+	}
+
+	steps := []struct {
+		query string
+		emit  func(rows *sql.Rows) error
+	}{
+		{`SELECT name, path, pattern, COALESCE(created_at, ''), max_file_size FROM collections
+			WHERE name IN ` + in + ` ORDER BY name`,
+			scanEmit(write("collection", &stats.Collections), func(rows *sql.Rows) (any, error) {
+				var c archiveCollection
+				err := rows.Scan(&c.Name, &c.Path, &c.Pattern, &c.CreatedAt, &c.MaxFileSize)
+				return c, err
+			})},
+		{`SELECT collection, path, context, created_at FROM contexts
+			WHERE collection IN ` + in + ` ORDER BY collection, path`,
+			scanEmit(write("context", &stats.Contexts), func(rows *sql.Rows) (any, error) {
+				var c archiveContext
+				err := rows.Scan(&c.Collection, &c.Path, &c.Context, &c.CreatedAt)
+				return c, err
+			})},
+		{`SELECT hash, doc, created_at FROM content WHERE hash IN (` + exportedHashes + `) ORDER BY hash`,
+			scanEmit(write("content", &stats.Content), func(rows *sql.Rows) (any, error) {
+				var c archiveContent
+				err := rows.Scan(&c.Hash, &c.Doc, &c.CreatedAt)
+				return c, err
+			})},
+		{`SELECT collection, path, title, hash, created_at, modified_at, active FROM documents
+			WHERE collection IN ` + in + ` ORDER BY collection, path`,
+			scanEmit(write("document", &stats.Documents), func(rows *sql.Rows) (any, error) {
+				var d archiveDocument
+				err := rows.Scan(&d.Collection, &d.Path, &d.Title, &d.Hash, &d.CreatedAt, &d.ModifiedAt, &d.Active)
+				return d, err
+			})},
+		{`SELECT hash, chunk_idx, from_line, to_line, breadcrumb, text FROM chunks
+			WHERE hash IN (` + exportedHashes + `) ORDER BY hash, chunk_idx`,
+			scanEmit(write("chunk", &stats.Chunks), func(rows *sql.Rows) (any, error) {
+				var c archiveChunk
+				err := rows.Scan(&c.Hash, &c.ChunkIdx, &c.FromLine, &c.ToLine, &c.Breadcrumb, &c.Text)
+				return c, err
+			})},
+		{`SELECT hash, chunk_idx, model, dimensions, vector, created_at FROM embeddings
+			WHERE hash IN (` + exportedHashes + `) ORDER BY hash, chunk_idx`,
+			scanEmit(write("embedding", &stats.Embeddings), func(rows *sql.Rows) (any, error) {
+				var e archiveEmbedding
+				err := rows.Scan(&e.Hash, &e.ChunkIdx, &e.Model, &e.Dimensions, &e.Vector, &e.CreatedAt)
+				return e, err
+			})},
+	}
+	for _, step := range steps {
+		rows, err := s.db.Query(step.query, args...)
+		if err != nil {
+			return nil, err
+		}
+		err = step.emit(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// scanEmit writes each scanned row as an archive record
+func scanEmit(emit func(v any) error, scan func(rows *sql.Rows) (any, error)) func(rows *sql.Rows) error {
+	return func(rows *sql.Rows) error {
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				return err
+			}
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+}
+
+// embeddingModels lists the model and dimension pairs in the embeddings
+// table, optionally filtered by a WHERE clause
+func (s *Store) embeddingModels(where string, args ...any) ([]ModelUsage, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT model, dimensions FROM embeddings `+where+` ORDER BY model, dimensions`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	models := []ModelUsage{}
+	for rows.Next() {
+		var m ModelUsage
+		if err := rows.Scan(&m.Model, &m.Dimensions); err != nil {
+			return nil, err
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	// Remap moves collection roots: a key is either a collection name,
+	// mapping to its new root, or a path prefix replaced by the value
+	Remap map[string]string
+	// Model is the embedding model in use. Embeddings from other models
+	// are skipped and listed in ArchiveStats.SkippedModels; empty imports
+	// every model.
+	Model string
+	// SkipEmbeddings imports everything but embeddings and chunks
+	SkipEmbeddings bool
+	// Replace drops existing collections of the same name first
+	Replace bool
+}
+
+// Import loads an archive written by Export in a single transaction
+func (s *Store) Import(r io.Reader, opts ImportOptions) (*ArchiveStats, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gqmd archive: %w", err)
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+
+	var header archiveHeader
+	if err := dec.Decode(&header); err != nil || header.Format != archiveFormat {
+		return nil, fmt.Errorf("not a gqmd archive")
+	}
+	if header.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than this gqmd supports (%d)", header.Version, ArchiveVersion)
+	}
+	if header.SchemaVersion > SchemaVersion() {
+		return nil, fmt.Errorf("archive schema version %d is newer than this gqmd supports (%d)", header.SchemaVersion, SchemaVersion())
+	}
+
+	stats := &ArchiveStats{Version: header.Version, Models: header.Models}
+	models := make(map[string]bool)
+	if !opts.SkipEmbeddings {
+		for _, m := range header.Models {
+			if opts.Model == "" || m.Model == opts.Model {
+				models[m.Model] = true
+			} else {
+				stats.SkippedModels = append(stats.SkippedModels, m)
+			}
+		}
+		if err := s.checkImportModels(header.Models, models); err != nil {
+			return nil, err
+		}
+	}
+
+	remap := make(map[string]string, len(opts.Remap))
+	for from, to := range opts.Remap {
+		remap[filepath.Clean(from)] = to
+	}
+	opts.Remap = remap
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for {
+		var rec archiveRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if err := importRecord(tx, rec, opts, models, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	// Replaced collections may leave content behind
+	if err := s.removeOrphans(&CleanupResult{}); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// checkImportModels rejects imported models whose embeddings would not be
+// comparable with embeddings of the same model already stored
+func (s *Store) checkImportModels(archived []ModelUsage, models map[string]bool) error {
+	existing, err := s.embeddingModels("")
+	if err != nil {
+		return err
+	}
+	for _, m := range archived {
+		if !models[m.Model] {
+			continue
+		}
+		for _, e := range existing {
+			if e.Model == m.Model && e.Dimensions != m.Dimensions {
+				return fmt.Errorf("archive has %d-dimension embeddings for %s, the index has %d", m.Dimensions, m.Model, e.Dimensions)
+			}
+		}
+	}
+	return nil
+}
+
+// importRecord writes one archive record; embeddings are only written for
+// the models being imported
+func importRecord(tx *sql.Tx, rec archiveRecord, opts ImportOptions, models map[string]bool, stats *ArchiveStats) error {
+	decode := func(v any) error {
+		if err := json.Unmarshal(rec.Data, v); err != nil {
+			return fmt.Errorf("bad %s record: %w", rec.Type, err)
+		}
+		return nil
+	}
+
+	switch rec.Type {
+	case "collection":
+		var c archiveCollection
+		if err := decode(&c); err != nil {
+			return err
+		}
+		c.Path = remapRoot(c.Name, c.Path, opts.Remap)
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM collections WHERE name = ?`, c.Name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			if !opts.Replace {
+				return fmt.Errorf("collection %q already exists, use --replace to overwrite it", c.Name)
+			}
+			if err := dropCollection(tx, c.Name); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(`INSERT INTO collections (name, path, pattern, created_at, max_file_size) VALUES (?, ?, ?, ?, ?)`,
+			c.Name, c.Path, c.Pattern, c.CreatedAt, c.MaxFileSize)
+		if err != nil {
+			return err
+		}
+		stats.Collections++
+
+	case "context":
+		var c archiveContext
+		if err := decode(&c); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT OR REPLACE INTO contexts (collection, path, context, created_at) VALUES (?, ?, ?, ?)`,
+			c.Collection, c.Path, c.Context, c.CreatedAt)
+		if err != nil {
+			return err
+		}
+		stats.Contexts++
+
+	case "content":
+		var c archiveContent
+		if err := decode(&c); err != nil {
+			return err
+		}
+		if hashContent([]byte(c.Doc)) != c.Hash {
+			return fmt.Errorf("content %s does not match its hash", DocID(c.Hash))
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO content (hash, doc, created_at) VALUES (?, ?, ?)`,
+			c.Hash, c.Doc, c.CreatedAt); err != nil {
+			return err
+		}
+		stats.Content++
+
+	case "document":
+		var d archiveDocument
+		if err := decode(&d); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO documents (collection, path, title, hash, created_at, modified_at, active)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			d.Collection, d.Path, d.Title, d.Hash, d.CreatedAt, d.ModifiedAt, d.Active)
+		if err != nil {
+			return fmt.Errorf("document %s/%s: %w", d.Collection, d.Path, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO documents_fts (rowid, filepath, title, body)
+			SELECT ?, ?, ?, doc FROM content WHERE hash = ?`,
+			id, d.Collection+"/"+d.Path, d.Title, d.Hash)
+		if err != nil {
+			return err
+		}
+		stats.Documents++
+
+	case "chunk":
+		if opts.SkipEmbeddings {
+			return nil
+		}
+		var c archiveChunk
+		if err := decode(&c); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO chunks (hash, chunk_idx, from_line, to_line, breadcrumb, text)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			c.Hash, c.ChunkIdx, c.FromLine, c.ToLine, c.Breadcrumb, c.Text)
+		if err != nil {
+			return err
+		}
+		stats.Chunks++
+
+	case "embedding":
+		if opts.SkipEmbeddings {
+			return nil
+		}
+		var e archiveEmbedding
+		if err := decode(&e); err != nil {
+			return err
+		}
+		if !models[e.Model] {
+			return nil
+		}
+		if len(e.Vector) != e.Dimensions*4 {
+			return fmt.Errorf("embedding for %s has %d bytes, want %d", DocID(e.Hash), len(e.Vector), e.Dimensions*4)
+		}
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO embeddings (hash, chunk_idx, model, dimensions, vector, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			e.Hash, e.ChunkIdx, e.Model, e.Dimensions, e.Vector, e.CreatedAt)
+		if err != nil {
+			return err
+		}
+		stats.Embeddings++
+
+	default:
+		// Records from newer minor additions are skipped
+	}
+	return nil
+}
+
+// dropCollection removes a collection with its documents, FTS rows and
+// contexts inside tx
+func dropCollection(tx *sql.Tx, name string) error {
+	stmts := []string{
+		`DELETE FROM documents_fts WHERE rowid IN (SELECT id FROM documents WHERE collection = ?)`,
+		`DELETE FROM documents WHERE collection = ?`,
+		`DELETE FROM contexts WHERE collection = ?`,
+		`DELETE FROM collections WHERE name = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapRoot applies ImportOptions.Remap, with cleaned keys, to a
+// collection root. A collection name match wins over the longest prefix.
+func remapRoot(name, root string, remap map[string]string) string {
+	if to, ok := remap[name]; ok {
+		return to
+	}
+	best := ""
+	for from := range remap {
+		if (root == from || strings.HasPrefix(root, from+string(filepath.Separator))) && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best == "" {
+		return root
+	}
+	return filepath.Join(remap[best], strings.TrimPrefix(root, best))
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newArchiveSource(t *testing.T) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "notes")
+	os.MkdirAll(filepath.Join(docs, "infra"), 0755)
+	os.WriteFile(filepath.Join(docs, "go.md"), []byte("# Go\n\nGoroutines and channels."), 0644)
+	os.WriteFile(filepath.Join(docs, "infra", "k8s.md"), []byte("# Kubernetes\n\nCluster upgrades."), 0644)
+
+	s, err := OpenPath(filepath.Join(dir, "src.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	s.AddCollection("notes", docs, "**/*.md")
+	if _, err := s.ScanCollection("notes"); err != nil {
+		t.Fatal(err)
+	}
+	s.AddContext("notes", "infra", "Infra team notes")
+	embed := func(text string) ([]float32, error) {
+		if strings.Contains(text, "Kubernetes") {
+			return []float32{0, 1}, nil
+		}
+		return []float32{1, 0}, nil
+	}
+	if _, err := s.EmbedDocuments("m", embed, EmbedOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return s, docs
+}
+
+func TestExportImport(t *testing.T) {
+	src, _ := newArchiveSource(t)
+
+	var archive bytes.Buffer
+	exported, err := src.Export(&archive, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if exported.Collections != 1 || exported.Documents != 2 || exported.Content != 2 ||
+		exported.Contexts != 1 || exported.Embeddings != 2 || exported.Chunks != 2 {
+		t.Errorf("Export stats = %+v", exported)
+	}
+
+	dst, err := OpenPath(filepath.Join(t.TempDir(), "dst.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	imported, err := dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{
+		Model: "m",
+		Remap: map[string]string{"notes": "/srv/notes"},
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported.Documents != 2 || imported.Embeddings != 2 {
+		t.Errorf("Import stats = %+v", imported)
+	}
+
+	col, err := dst.GetCollection("notes")
+	if err != nil || col.Path != "/srv/notes" {
+		t.Errorf("collection = %+v, %v", col, err)
+	}
+	results, err := dst.Search("kubernetes", 10)
+	if err != nil || len(results) != 1 || results[0].Context != "Infra team notes" {
+		t.Errorf("Search = %+v, %v", results, err)
+	}
+	vec, err := dst.VectorSearch(Vector{0, 1}, 1)
+	if err != nil || len(vec) != 1 || vec[0].Path != "infra/k8s.md" || vec[0].Text == "" {
+		t.Errorf("VectorSearch = %+v, %v", vec, err)
+	}
+	if pending, _ := dst.PendingEmbeddings("m", ""); len(pending) != 0 {
+		t.Errorf("PendingEmbeddings = %v, want none after import", pending)
+	}
+
+	// Importing again needs --replace
+	if _, err := dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{}); err == nil {
+		t.Error("expected error importing an existing collection")
+	}
+	if _, err := dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{Replace: true}); err != nil {
+		t.Fatalf("Import with Replace: %v", err)
+	}
+	if n := countRows(t, dst, "documents"); n != 2 {
+		t.Errorf("documents after replace = %d, want 2", n)
+	}
+	if n := countRows(t, dst, "documents_fts"); n != 2 {
+		t.Errorf("fts rows after replace = %d, want 2", n)
+	}
+}
+
+func TestImportCompatibility(t *testing.T) {
+	src, _ := newArchiveSource(t)
+	var archive bytes.Buffer
+	if _, err := src.Export(&archive, ExportOptions{Collections: []string{"notes"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	open := func() *Store {
+		s, err := OpenPath(filepath.Join(t.TempDir(), "dst.sqlite"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}
+
+	// Embeddings from a different model are skipped
+	dst := open()
+	stats, err := dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{Model: "other"})
+	if err != nil {
+		t.Fatalf("Import with other model: %v", err)
+	}
+	if len(stats.SkippedModels) != 1 || stats.SkippedModels[0].Model != "m" {
+		t.Errorf("SkippedModels = %+v, want [m]", stats.SkippedModels)
+	}
+	if stats.Documents != 2 || stats.Embeddings != 0 || countRows(t, dst, "embeddings") != 0 {
+		t.Errorf("Import with other model = %+v", stats)
+	}
+
+	// as are all of them with SkipEmbeddings
+	dst = open()
+	stats, err = dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{Model: "m", SkipEmbeddings: true})
+	if err != nil {
+		t.Fatalf("Import SkipEmbeddings: %v", err)
+	}
+	if stats.Embeddings != 0 || countRows(t, dst, "embeddings") != 0 {
+		t.Errorf("embeddings imported despite SkipEmbeddings")
+	}
+
+	// A multi-model archive imports only the matching model
+	if _, err := src.EmbedDocuments("m2", func(string) ([]float32, error) { return []float32{1, 0, 0}, nil }, EmbedOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var multi bytes.Buffer
+	if _, err := src.Export(&multi, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dst = open()
+	stats, err = dst.Import(bytes.NewReader(multi.Bytes()), ImportOptions{Model: "m2"})
+	if err != nil {
+		t.Fatalf("Import multi-model archive: %v", err)
+	}
+	if stats.Embeddings != 2 || len(stats.SkippedModels) != 1 || stats.SkippedModels[0].Model != "m" {
+		t.Errorf("Import multi-model archive = %+v", stats)
+	}
+	var models []string
+	rows, err := dst.db.Query(`SELECT DISTINCT model FROM embeddings`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var m string
+		rows.Scan(&m)
+		models = append(models, m)
+	}
+	rows.Close()
+	if len(models) != 1 || models[0] != "m2" {
+		t.Errorf("imported models = %v, want [m2]", models)
+	}
+
+	// Existing embeddings of the same model with other dimensions conflict
+	dst = open()
+	dst.StoreEmbedding("x", 0, "m", Vector{1, 0, 0})
+	if _, err := dst.Import(bytes.NewReader(archive.Bytes()), ImportOptions{}); err == nil {
+		t.Error("expected dimension mismatch error")
+	}
+
+	// Newer archive versions and other files are rejected
+	var newer bytes.Buffer
+	zw := gzip.NewWriter(&newer)
+	zw.Write([]byte(`{"format":"gqmd-archive","version":99}` + "\n"))
+	zw.Close()
+	if _, err := open().Import(&newer, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Import newer = %v", err)
+	}
+	newer.Reset()
+	zw = gzip.NewWriter(&newer)
+	zw.Write([]byte(`{"format":"gqmd-archive","version":1,"schema_version":99}` + "\n"))
+	zw.Close()
+	if _, err := open().Import(&newer, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("Import newer schema = %v", err)
+	}
+	if _, err := open().Import(strings.NewReader("not an archive"), ImportOptions{}); err == nil {
+		t.Error("expected error for non-archive input")
+	}
+
+	if _, err := src.Export(&bytes.Buffer{}, ExportOptions{Collections: []string{"missing"}}); err == nil {
+		t.Error("expected error exporting a missing collection")
+	}
+}
+
+func TestRemapRoot(t *testing.T) {
+	remap := map[string]string{
+		"notes":          "/new/notes",
+		"/home/a":        "/home/b",
+		"/home/a/shared": "/mnt/shared",
+	}
+	tests := []struct {
+		name, root, want string
+	}{
+		{"notes", "/home/a/notes", "/new/notes"},
+		{"docs", "/home/a/docs", "/home/b/docs"},
+		{"team", "/home/a/shared/team", "/mnt/shared/team"},
+		{"root", "/home/a", "/home/b"},
+		{"other", "/home/ab/docs", "/home/ab/docs"},
+	}
+	for _, tt := range tests {
+		if got := remapRoot(tt.name, tt.root, remap); got != tt.want {
+			t.Errorf("remapRoot(%s, %s) = %s, want %s", tt.name, tt.root, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/extract"
+)
+
+// DefaultChunkBytes is the target chunk size for embeddings, roughly 500 tokens
+const DefaultChunkBytes = 2000
+
+// Chunk is a piece of a document that is embedded on its own
+type Chunk struct {
+	Index      int
+	Text       string
+	FromLine   int // 1-based, inclusive
+	ToLine     int // 1-based, inclusive
+	Breadcrumb string
+}
+
+// ChunkContent splits a markdown document into chunks at headings, and
+// splits sections larger than maxBytes on line boundaries. Each chunk
+// carries the heading path it sits under, e.g. "Setup > Linux".
+func ChunkContent(content string, maxBytes int) []Chunk {
+	if maxBytes <= 0 {
+		maxBytes = DefaultChunkBytes
+	}
+	lines := extract.SplitLines(content)
+	headings := ParseHeadings(content)
+
+	var chunks []Chunk
+	add := func(from, to int, crumb string) {
+		if strings.TrimSpace(strings.Join(lines[from-1:to], "")) == "" {
+			return
+		}
+		// Split oversized sections on line boundaries
+		start, size := from, 0
+		for i := from; i <= to; i++ {
+			n := len(lines[i-1]) + 1
+			if size > 0 && size+n > maxBytes {
+				chunks = append(chunks, newChunk(lines, len(chunks), start, i-1, crumb))
+				start, size = i, 0
+			}
+			size += n
+		}
+		chunks = append(chunks, newChunk(lines, len(chunks), start, to, crumb))
+	}
+
+	// Text before the first heading
+	preamble := len(lines)
+	if len(headings) > 0 {
+		preamble = headings[0].Line - 1
+	}
+	if preamble > 0 {
+		add(1, preamble, "")
+	}
+
+	var stack []Heading
+	for i, h := range headings {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+
+		end := len(lines)
+		if i+1 < len(headings) {
+			end = headings[i+1].Line - 1
+		}
+		add(h.Line, end, breadcrumb(stack))
+	}
+
+	return chunks
+}
+
+func newChunk(lines []string, idx, from, to int, breadcrumb string) Chunk {
+	return Chunk{
+		Index:      idx,
+		Text:       strings.Join(lines[from-1:to], "\n"),
+		FromLine:   from,
+		ToLine:     to,
+		Breadcrumb: breadcrumb,
+	}
+}
+
+func breadcrumb(stack []Heading) string {
+	parts := make([]string, len(stack))
+	for i, h := range stack {
+		parts[i] = h.Text
+	}
+	return strings.Join(parts, " > ")
+}
+
+// trimText shortens text to about n bytes on a word boundary, never
+// splitting a UTF-8 rune
+func trimText(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if n <= 0 || len(text) <= n {
+		return text
+	}
+	cut := truncateContent(text, n)
+	if idx := strings.LastIndexByte(cut, ' '); idx > n/2 {
+		cut = cut[:idx]
+	}
+	return cut + "..."
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CleanupOptions configures Cleanup
+type CleanupOptions struct {
+	// Vacuum rebuilds the database file and optimizes the FTS index so
+	// freed space is returned to the filesystem
+	Vacuum bool
+}
+
+// CleanupResult reports what Cleanup removed
+type CleanupResult struct {
+	Content     int
+	FTSRows     int
+	Embeddings  int
+	Chunks      int
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// Removed is the total number of rows removed
+func (r *CleanupResult) Removed() int {
+	return r.Content + r.FTSRows + r.Embeddings + r.Chunks
+}
+
+// Reclaimed is how much smaller the database file got
+func (r *CleanupResult) Reclaimed() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// Cleanup deletes content, FTS rows, embeddings and chunks no document
+// refers to any more, left behind by edits and removed collections
+func (s *Store) Cleanup(opts CleanupOptions) (*CleanupResult, error) {
+	result := &CleanupResult{}
+	var err error
+	if result.BytesBefore, err = s.dbSize(); err != nil {
+		return nil, err
+	}
+
+	if err := s.removeOrphans(result); err != nil {
+		return nil, err
+	}
+
+	if opts.Vacuum {
+		if _, err := s.db.Exec(`INSERT INTO documents_fts(documents_fts) VALUES('optimize')`); err != nil {
+			return nil, fmt.Errorf("optimize fts: %w", err)
+		}
+		if _, err := s.db.Exec(`VACUUM`); err != nil {
+			return nil, fmt.Errorf("vacuum: %w", err)
+		}
+	}
+
+	if result.BytesAfter, err = s.dbSize(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// removeOrphans deletes unreferenced rows in one transaction
+func (s *Store) removeOrphans(result *CleanupResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		count *int
+		query string
+	}{
+		{&result.FTSRows, `DELETE FROM documents_fts WHERE rowid NOT IN (SELECT id FROM documents)`},
+		{&result.Chunks, `DELETE FROM chunks WHERE hash NOT IN (SELECT hash FROM documents)`},
+		{&result.Embeddings, `DELETE FROM embeddings WHERE hash NOT IN (SELECT hash FROM documents)`},
+		{&result.Content, `DELETE FROM content WHERE hash NOT IN (SELECT hash FROM documents)`},
+	}
+	for _, step := range steps {
+		n, err := execCount(tx, step.query)
+		if err != nil {
+			return err
+		}
+		*step.count = n
+	}
+	return tx.Commit()
+}
+
+func execCount(tx *sql.Tx, query string) (int, error) {
+	res, err := tx.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// dbSize returns the size of the main database file in bytes
+func (s *Store) dbSize() (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func countRows(t *testing.T, s *Store, table string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCleanup(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// An edit leaves the old content and its embedding behind
+	s.IndexDocument("notes", "a.md", "A", "old text "+strings.Repeat("x", 20000), "oldhash")
+	s.StoreEmbedding("oldhash", 0, "m", Vector{1, 0})
+	s.StoreChunk("oldhash", Chunk{Index: 0, Text: "old text", FromLine: 1, ToLine: 1})
+	s.IndexDocument("notes", "a.md", "A", "new text", "newhash")
+	s.StoreEmbedding("newhash", 0, "m", Vector{0, 1})
+	// An FTS row whose document is gone
+	s.db.Exec(`INSERT INTO documents_fts (rowid, filepath, title, body) VALUES (999, 'x/y.md', 'Y', 'stale')`)
+
+	result, err := s.Cleanup(CleanupOptions{Vacuum: true})
+	if err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+	if result.Content != 1 || result.Embeddings != 1 || result.Chunks != 1 || result.FTSRows != 1 {
+		t.Errorf("Cleanup = %+v", result)
+	}
+	if result.Reclaimed() <= 0 {
+		t.Errorf("Reclaimed = %d, want > 0", result.Reclaimed())
+	}
+
+	// The live document is untouched
+	if _, content, err := s.Get("notes", "a.md"); err != nil || content != "new text" {
+		t.Errorf("Get = %q, %v", content, err)
+	}
+	if n := countRows(t, s, "embeddings"); n != 1 {
+		t.Errorf("embeddings = %d, want 1", n)
+	}
+	if results, _ := s.Search("stale", 10); len(results) != 0 {
+		t.Errorf("orphaned FTS row still searchable: %+v", results)
+	}
+
+	// Nothing left to do the second time
+	result, err = s.Cleanup(CleanupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed() != 0 {
+		t.Errorf("second Cleanup removed %d rows", result.Removed())
+	}
+}
+
+func TestRemoveCollectionCleansUp(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.AddCollection("a", "/a", "**/*.md")
+	s.AddCollection("b", "/b", "**/*.md")
+	s.IndexDocument("a", "only.md", "Only", "only in a", "h1")
+	s.IndexDocument("a", "shared.md", "Shared", "shared", "h2")
+	s.IndexDocument("b", "shared.md", "Shared", "shared", "h2")
+	s.StoreEmbedding("h1", 0, "m", Vector{1})
+	s.StoreEmbedding("h2", 0, "m", Vector{1})
+
+	if err := s.RemoveCollection("a"); err != nil {
+		t.Fatalf("RemoveCollection: %v", err)
+	}
+	if n := countRows(t, s, "content"); n != 1 {
+		t.Errorf("content = %d, want 1 shared row", n)
+	}
+	if n := countRows(t, s, "embeddings"); n != 1 {
+		t.Errorf("embeddings = %d, want 1", n)
+	}
+	if n := countRows(t, s, "documents_fts"); n != 1 {
+		t.Errorf("fts rows = %d, want 1", n)
+	}
+}
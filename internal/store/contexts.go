@@ -0,0 +1,110 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathContext is a human description attached to a collection or a
+// folder or file within it. Path is empty for the collection root.
+type PathContext struct {
+	Collection string
+	Path       string
+	Context    string
+	CreatedAt  string
+}
+
+func normalizeContextPath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// AddContext attaches a description to a collection path, replacing any
+// existing one
+func (s *Store) AddContext(collection, path, text string) error {
+	if _, err := s.GetCollection(collection); err != nil {
+		return fmt.Errorf("collection %q not found", collection)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO contexts (collection, path, context, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(collection, path) DO UPDATE SET context = excluded.context`,
+		collection, normalizeContextPath(path), text, nowISO(),
+	)
+	return err
+}
+
+// ListContexts returns all path contexts ordered by collection and path
+func (s *Store) ListContexts() ([]PathContext, error) {
+	rows, err := s.db.Query(
+		`SELECT collection, path, context, created_at FROM contexts ORDER BY collection, path`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []PathContext
+	for rows.Next() {
+		var c PathContext
+		if err := rows.Scan(&c.Collection, &c.Path, &c.Context, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+	return contexts, rows.Err()
+}
+
+// RemoveContext deletes the description attached to a collection path
+func (s *Store) RemoveContext(collection, path string) error {
+	result, err := s.db.Exec(
+		`DELETE FROM contexts WHERE collection = ? AND path = ?`,
+		collection, normalizeContextPath(path),
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no context for %s/%s", collection, normalizeContextPath(path))
+	}
+	return nil
+}
+
+// contextIndex resolves inherited contexts for many documents from one query
+type contextIndex map[string][]PathContext
+
+func (s *Store) loadContexts() (contextIndex, error) {
+	all, err := s.ListContexts()
+	if err != nil {
+		return nil, err
+	}
+	idx := make(contextIndex)
+	for _, c := range all {
+		idx[c.Collection] = append(idx[c.Collection], c)
+	}
+	return idx, nil
+}
+
+// lookup returns the contexts of every ancestor of path, from the
+// collection root down to the document itself, joined by newlines
+func (idx contextIndex) lookup(collection, path string) string {
+	var parts []string
+	// Contexts are ordered by path, so ancestors come before descendants
+	for _, c := range idx[collection] {
+		if c.Path == "" || path == c.Path || strings.HasPrefix(path, c.Path+"/") {
+			parts = append(parts, c.Context)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ContextFor returns the inherited context of a document path
+func (s *Store) ContextFor(collection, path string) (string, error) {
+	idx, err := s.loadContexts()
+	if err != nil {
+		return "", err
+	}
+	return idx.lookup(collection, path), nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Page describes where a result list sits in the full result set
+type Page struct {
+	Offset     int
+	HasMore    bool
+	NextCursor string // pass as SearchOptions.Cursor to fetch the next page
+}
+
+// fingerprint identifies the query and options a cursor was issued for
+func fingerprint(data []byte) string {
+	h := fnv.New32a()
+	h.Write(data)
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+// encodeCursor returns an opaque cursor for the result at offset
+func encodeCursor(kind, query string, offset int) string {
+	raw := fmt.Sprintf("%s:%s:%d", kind, fingerprint([]byte(query)), offset)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns the offset stored in a cursor, checking that it
+// was issued for the same kind of search and query
+func decodeCursor(cursor, kind, query string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	if parts[0] != kind || parts[1] != fingerprint([]byte(query)) {
+		return 0, fmt.Errorf("cursor was issued for a different query or options")
+	}
+	offset, err := strconv.Atoi(parts[2])
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
+
+// cursorScope is what a cursor is bound to: the query and every option
+// that changes the result set, so a page cannot be read with other options
+func (o SearchOptions) cursorScope(query string) string {
+	return fmt.Sprintf("%s\x00%g\x00%t\x00%s", query, o.MinScore, o.Collapse, o.Model)
+}
+
+// resolvePage returns the offset to start from, preferring the cursor
+func (o SearchOptions) resolvePage(kind, query string) (int, error) {
+	if o.Cursor != "" {
+		return decodeCursor(o.Cursor, kind, o.cursorScope(query))
+	}
+	if o.Offset < 0 {
+		return 0, nil
+	}
+	return o.Offset, nil
+}
+
+func (o SearchOptions) newPage(kind, query string, offset, count int, hasMore bool) *Page {
+	p := &Page{Offset: offset, HasMore: hasMore}
+	if hasMore {
+		p.NextCursor = encodeCursor(kind, o.cursorScope(query), offset+count)
+	}
+	return p
+}
@@ -3,10 +3,13 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
@@ -17,19 +20,15 @@ type Store struct {
 	dbPath string
 }
 
-type Status struct {
-	DBPath         string
-	TotalDocs      int
-	Collections    int
-	HasVectorIndex bool
-}
-
 type Collection struct {
 	ID        int64
 	Name      string
 	Path      string
 	Pattern   string
 	CreatedAt string
+	// MaxFileSize is the largest file a scan indexes, in bytes; 0 means
+	// DefaultMaxFileSize and a negative value means no limit
+	MaxFileSize int64
 }
 
 type Document struct {
@@ -38,12 +37,49 @@ type Document struct {
 	Path       string
 	Title      string
 	Hash       string
+	DocID      string
+	Context    string
 	CreatedAt  string
 	ModifiedAt string
 	Active     bool
 }
 
-func getDBPath() (string, error) {
+// DefaultIndex is the name of the index used when none is given
+const DefaultIndex = "index"
+
+// ResolvePath picks the database file: an explicit path first, then a
+// named index, then $GQMD_DB, then the default index. Named indexes live
+// in $XDG_CACHE_HOME/gqmd/<name>.sqlite.
+func ResolvePath(dbPath, index string) (string, error) {
+	if dbPath != "" && index != "" {
+		return "", fmt.Errorf("use either a database path or an index name, not both")
+	}
+	if dbPath != "" {
+		return dbPath, nil
+	}
+	if index == "" {
+		if env := os.Getenv("GQMD_DB"); env != "" {
+			return env, nil
+		}
+		index = DefaultIndex
+	}
+	if err := validateIndexName(index); err != nil {
+		return "", err
+	}
+	return getDBPath(index)
+}
+
+func validateIndexName(name string) error {
+	for _, r := range name {
+		ok := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+		if !ok {
+			return fmt.Errorf("invalid index name %q: use letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+func getDBPath(index string) (string, error) {
 	cacheDir := os.Getenv("XDG_CACHE_HOME")
 	if cacheDir == "" {
 		home, err := os.UserHomeDir()
@@ -56,19 +92,28 @@ func getDBPath() (string, error) {
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
 		return "", err
 	}
-	return filepath.Join(dbDir, "index.sqlite"), nil
+	return filepath.Join(dbDir, index+".sqlite"), nil
 }
 
+// Open opens the default index, or $GQMD_DB if set
 func Open() (*Store, error) {
-	dbPath, err := getDBPath()
+	dbPath, err := ResolvePath("", "")
 	if err != nil {
 		return nil, fmt.Errorf("get db path: %w", err)
 	}
 	return OpenPath(dbPath)
 }
 
+// busyTimeout is how long a connection waits for another process's write
+// lock, such as the watcher or serve writing while a CLI command runs
+const busyTimeout = 30 * time.Second
+
 func OpenPath(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	dsn, err := sqliteDSN(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
@@ -81,108 +126,31 @@ func OpenPath(dbPath string) (*Store, error) {
 	return s, nil
 }
 
-func (s *Store) init() error {
-	// Collections table
-	_, err := s.db.Exec(`
-	CREATE TABLE IF NOT EXISTS collections (
-		id INTEGER PRIMARY KEY,
-		name TEXT UNIQUE NOT NULL,
-		path TEXT NOT NULL,
-		pattern TEXT DEFAULT '**/*.md',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	if err != nil {
-		return err
-	}
-
-	// Content-addressable storage
-	_, err = s.db.Exec(`
-	CREATE TABLE IF NOT EXISTS content (
-		hash TEXT PRIMARY KEY,
-		doc TEXT NOT NULL,
-		created_at TEXT NOT NULL
-	)`)
+// sqliteDSN returns the URI opening dbPath with a busy timeout on every
+// connection. Write transactions take the write lock when they begin, so
+// two writers wait for each other instead of failing with SQLITE_BUSY
+// when a read lock cannot be upgraded.
+func sqliteDSN(dbPath string) (string, error) {
+	abs, err := filepath.Abs(dbPath)
 	if err != nil {
-		return err
-	}
-
-	// Documents table
-	_, err = s.db.Exec(`
-	CREATE TABLE IF NOT EXISTS documents (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		collection TEXT NOT NULL,
-		path TEXT NOT NULL,
-		title TEXT NOT NULL,
-		hash TEXT NOT NULL,
-		created_at TEXT NOT NULL,
-		modified_at TEXT NOT NULL,
-		active INTEGER NOT NULL DEFAULT 1,
-		FOREIGN KEY (hash) REFERENCES content(hash) ON DELETE CASCADE,
-		UNIQUE(collection, path)
-	)`)
-	if err != nil {
-		return err
-	}
-
-	// Indexes
-	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_documents_collection ON documents(collection, active)`)
-	if err != nil {
-		return err
-	}
-	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_documents_hash ON documents(hash)`)
-	if err != nil {
-		return err
-	}
-
-	// FTS5 virtual table
-	_, err = s.db.Exec(`
-	CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
-		filepath, title, body,
-		tokenize='porter unicode61'
-	)`)
-	if err != nil {
-		return err
+		return "", err
 	}
-
-	// Vector embeddings table
-	_, err = s.db.Exec(`
-	CREATE TABLE IF NOT EXISTS embeddings (
-		hash TEXT NOT NULL,
-		chunk_idx INTEGER NOT NULL DEFAULT 0,
-		model TEXT NOT NULL,
-		dimensions INTEGER NOT NULL,
-		vector BLOB NOT NULL,
-		created_at TEXT NOT NULL,
-		PRIMARY KEY (hash, chunk_idx)
-	)`)
-	if err != nil {
-		return err
+	u := url.URL{
+		Scheme:   "file",
+		Path:     filepath.ToSlash(abs),
+		RawQuery: fmt.Sprintf("_pragma=busy_timeout(%d)&_txlock=immediate", busyTimeout.Milliseconds()),
 	}
+	return u.String(), nil
+}
 
-	return nil
+func (s *Store) init() error {
+	return s.migrate()
 }
 
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-func (s *Store) GetStatus() (*Status, error) {
-	status := &Status{DBPath: s.dbPath}
-
-	row := s.db.QueryRow("SELECT COUNT(*) FROM collections")
-	if err := row.Scan(&status.Collections); err != nil {
-		return nil, err
-	}
-
-	row = s.db.QueryRow("SELECT COUNT(*) FROM documents WHERE active = 1")
-	if err := row.Scan(&status.TotalDocs); err != nil {
-		return nil, err
-	}
-
-	status.HasVectorIndex = false
-	return status, nil
-}
-
 // Collection management
 
 func (s *Store) AddCollection(name, path, pattern string) error {
@@ -196,9 +164,26 @@ func (s *Store) AddCollection(name, path, pattern string) error {
 	return err
 }
 
+// SetMaxFileSize sets the largest file a collection's scans index; 0
+// restores DefaultMaxFileSize and a negative size removes the limit
+func (s *Store) SetMaxFileSize(name string, size int64) error {
+	result, err := s.db.Exec(`UPDATE collections SET max_file_size = ? WHERE name = ?`, size, name)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("collection %q not found", name)
+	}
+	return nil
+}
+
 func (s *Store) ListCollections() ([]Collection, error) {
 	rows, err := s.db.Query(
-		`SELECT id, name, path, pattern, created_at FROM collections ORDER BY name`,
+		`SELECT id, name, path, pattern, created_at, max_file_size FROM collections ORDER BY name`,
 	)
 	if err != nil {
 		return nil, err
@@ -208,7 +193,7 @@ func (s *Store) ListCollections() ([]Collection, error) {
 	var collections []Collection
 	for rows.Next() {
 		var c Collection
-		if err := rows.Scan(&c.ID, &c.Name, &c.Path, &c.Pattern, &c.CreatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.Name, &c.Path, &c.Pattern, &c.CreatedAt, &c.MaxFileSize); err != nil {
 			return nil, err
 		}
 		collections = append(collections, c)
@@ -218,11 +203,11 @@ func (s *Store) ListCollections() ([]Collection, error) {
 
 func (s *Store) GetCollection(name string) (*Collection, error) {
 	row := s.db.QueryRow(
-		`SELECT id, name, path, pattern, created_at FROM collections WHERE name = ?`,
+		`SELECT id, name, path, pattern, created_at, max_file_size FROM collections WHERE name = ?`,
 		name,
 	)
 	var c Collection
-	if err := row.Scan(&c.ID, &c.Name, &c.Path, &c.Pattern, &c.CreatedAt); err != nil {
+	if err := row.Scan(&c.ID, &c.Name, &c.Path, &c.Pattern, &c.CreatedAt, &c.MaxFileSize); err != nil {
 		return nil, err
 	}
 	return &c, nil
@@ -241,6 +226,12 @@ func (s *Store) RemoveCollection(name string) error {
 		return err
 	}
 
+	// Delete path contexts
+	_, err = tx.Exec(`DELETE FROM contexts WHERE collection = ?`, name)
+	if err != nil {
+		return err
+	}
+
 	// Delete collection
 	result, err := tx.Exec(`DELETE FROM collections WHERE name = ?`, name)
 	if err != nil {
@@ -255,27 +246,66 @@ func (s *Store) RemoveCollection(name string) error {
 		return fmt.Errorf("collection %q not found", name)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Drop the content, FTS rows and embeddings only this collection used
+	return s.removeOrphans(&CleanupResult{})
 }
 
 // Document indexing
 
 func (s *Store) IndexDocument(collection, path, title, content, hash string) error {
-	now := nowISO()
-
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if _, err := indexDocumentTx(tx, collection, path, title, content, hash, nowISO()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// docChange is what indexing did to a document
+type docChange int
+
+const (
+	docUnchanged docChange = iota
+	docAdded
+	docUpdated
+)
+
+// indexDocumentTx upserts a document, its content and its FTS entry
+// inside tx. It writes nothing when the document is already indexed with
+// this content and title.
+func indexDocumentTx(tx *sql.Tx, collection, path, title, content, hash, now string) (docChange, error) {
+	var oldHash, oldTitle string
+	var active bool
+	err := tx.QueryRow(
+		`SELECT hash, title, active FROM documents WHERE collection = ? AND path = ?`,
+		collection, path,
+	).Scan(&oldHash, &oldTitle, &active)
+	if err == nil && active && oldHash == hash && oldTitle == title {
+		return docUnchanged, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return docUnchanged, err
+	}
+	change := docUpdated
+	if err == sql.ErrNoRows || !active {
+		change = docAdded
+	}
+
 	// Insert content (ignore if exists)
 	_, err = tx.Exec(
 		`INSERT OR IGNORE INTO content (hash, doc, created_at) VALUES (?, ?, ?)`,
 		hash, content, now,
 	)
 	if err != nil {
-		return err
+		return docUnchanged, err
 	}
 
 	// Upsert document
@@ -284,13 +314,14 @@ func (s *Store) IndexDocument(collection, path, title, content, hash string) err
 		VALUES (?, ?, ?, ?, ?, ?, 1)
 		ON CONFLICT(collection, path) DO UPDATE SET
 			title = excluded.title,
+			modified_at = CASE WHEN documents.hash = excluded.hash
+				THEN documents.modified_at ELSE excluded.modified_at END,
 			hash = excluded.hash,
-			modified_at = excluded.modified_at,
 			active = 1`,
 		collection, path, title, hash, now, now,
 	)
 	if err != nil {
-		return err
+		return docUnchanged, err
 	}
 
 	// Get document ID for FTS
@@ -300,24 +331,88 @@ func (s *Store) IndexDocument(collection, path, title, content, hash string) err
 		collection, path,
 	).Scan(&docID)
 	if err != nil {
-		return err
+		return docUnchanged, err
 	}
 
 	// Update FTS index
 	filepath := collection + "/" + path
 	_, err = tx.Exec(`DELETE FROM documents_fts WHERE rowid = ?`, docID)
 	if err != nil {
-		return err
+		return docUnchanged, err
 	}
 	_, err = tx.Exec(
 		`INSERT INTO documents_fts (rowid, filepath, title, body) VALUES (?, ?, ?, ?)`,
 		docID, filepath, title, content,
 	)
+	if err != nil {
+		return docUnchanged, err
+	}
+	return change, nil
+}
+
+// RemoveDocument deletes a document and its FTS entry
+func (s *Store) RemoveDocument(collection, path string) error {
+	n, err := s.removeDocuments(`collection = ? AND path = ?`, collection, path)
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return fmt.Errorf("document %s/%s not found", collection, path)
+	}
+	return nil
+}
+
+// RemoveDocumentsUnder deletes every document of a collection below a
+// directory, returning how many were removed
+func (s *Store) RemoveDocumentsUnder(collection, dir string) (int, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	return s.removeDocuments(`collection = ? AND instr(path, ?) = 1`, collection, prefix)
+}
+
+func (s *Store) removeDocuments(where string, args ...any) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	return tx.Commit()
+	n, err := removeDocumentsTx(tx, where, args...)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// removeDocumentsTx deletes the documents matching where, and their FTS
+// rows, inside tx
+func removeDocumentsTx(tx *sql.Tx, where string, args ...any) (int, error) {
+	rows, err := tx.Query(`SELECT id FROM documents WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM documents_fts WHERE rowid = ?`, id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM documents WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
 }
 
 func nowISO() string {
@@ -327,44 +422,148 @@ func nowISO() string {
 // Search result types
 
 type SearchResult struct {
-	Collection string
-	Path       string
-	Title      string
-	Snippet    string
-	Score      float64
+	Collection  string
+	Path        string
+	Title       string
+	DocID       string
+	Context     string
+	Snippet     string
+	Score       float64      // normalized to 0-1, higher is better
+	Explanation *Explanation // set when SearchOptions.Explain is true
+}
+
+// Explanation breaks a result score down into its parts. BM25 values are
+// raw FTS5 scores, where more negative is a better match.
+type Explanation struct {
+	Rank         int
+	BM25         float64
+	FilepathBM25 float64
+	TitleBM25    float64
+	BodyBM25     float64
+	Similarity   float64
+}
+
+func (e *Explanation) String() string {
+	if e.BM25 == 0 {
+		return fmt.Sprintf("rank %d, similarity %.3f", e.Rank, e.Similarity)
+	}
+	return fmt.Sprintf("rank %d, bm25 %.3f (filepath %.3f, title %.3f, body %.3f)",
+		e.Rank, e.BM25, e.FilepathBM25, e.TitleBM25, e.BodyBM25)
+}
+
+// SearchOptions configures a search
+type SearchOptions struct {
+	Limit    int
+	Offset   int
+	Cursor   string  // opaque cursor from a previous Page, overrides Offset
+	MinScore float64 // drop results whose normalized score is lower
+	Explain  bool
+
+	// Vector search only
+	Model         string // embedding model of the query vector, empty for all
+	SnippetLength int    // bytes of chunk text per hit, negative for all
+	Collapse      bool   // keep only the best chunk of each document
+}
+
+// DefaultSnippetLength is the chunk text length of a vector hit
+const DefaultSnippetLength = 300
+
+// normalizeBM25 maps a raw FTS5 bm25 score (negative, lower is better) to 0-1
+func normalizeBM25(score float64) float64 {
+	score = math.Abs(score)
+	return score / (1 + score)
+}
+
+// bm25Threshold returns the raw bm25 score matching a normalized minimum
+func bm25Threshold(minScore float64) float64 {
+	return -minScore / (1 - minScore)
 }
 
 // Search performs FTS5 full-text search
 func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
+	results, _, err := s.SearchWithOptions(query, SearchOptions{Limit: limit})
+	return results, err
+}
+
+// SearchWithOptions performs FTS5 full-text search with paging, a score
+// threshold and optional per-column score explanations. Results are
+// ordered by score, then document id, so pages are stable.
+func (s *Store) SearchWithOptions(query string, opts SearchOptions) ([]SearchResult, *Page, error) {
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 10
 	}
+	offset, err := opts.resolvePage("fts", query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.MinScore >= 1 {
+		return nil, &Page{Offset: offset}, nil
+	}
+	maxBM25 := 0.0 // FTS5 bm25 scores are always negative
+	if opts.MinScore > 0 {
+		maxBM25 = bm25Threshold(opts.MinScore)
+	}
+
+	contexts, err := s.loadContexts()
+	if err != nil {
+		return nil, nil, err
+	}
 
+	// Fetch one extra row to tell whether there is a next page
 	rows, err := s.db.Query(`
-		SELECT d.collection, d.path, d.title,
+		SELECT d.collection, d.path, d.title, d.hash,
 			snippet(documents_fts, 2, '<mark>', '</mark>', '...', 32) as snippet,
-			bm25(documents_fts) as score
+			bm25(documents_fts) as score,
+			bm25(documents_fts, 1.0, 0.0, 0.0),
+			bm25(documents_fts, 0.0, 1.0, 0.0),
+			bm25(documents_fts, 0.0, 0.0, 1.0)
 		FROM documents_fts f
 		JOIN documents d ON d.id = f.rowid
-		WHERE documents_fts MATCH ? AND d.active = 1
-		ORDER BY score
-		LIMIT ?`,
-		query, limit,
+		WHERE documents_fts MATCH ? AND d.active = 1 AND bm25(documents_fts) <= ?
+		ORDER BY score, d.id
+		LIMIT ? OFFSET ?`,
+		query, maxBM25, limit+1, offset,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var results []SearchResult
+	var hashes []string
+	hasMore := false
 	for rows.Next() {
+		if len(results) == limit {
+			hasMore = true
+			break
+		}
 		var r SearchResult
-		if err := rows.Scan(&r.Collection, &r.Path, &r.Title, &r.Snippet, &r.Score); err != nil {
-			return nil, err
+		var hash string
+		var ex Explanation
+		if err := rows.Scan(&r.Collection, &r.Path, &r.Title, &hash, &r.Snippet, &ex.BM25,
+			&ex.FilepathBM25, &ex.TitleBM25, &ex.BodyBM25); err != nil {
+			return nil, nil, err
+		}
+		r.Context = contexts.lookup(r.Collection, r.Path)
+		r.Score = normalizeBM25(ex.BM25)
+		if opts.Explain {
+			ex.Rank = offset + len(results) + 1
+			r.Explanation = &ex
 		}
 		results = append(results, r)
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+	for i, hash := range hashes {
+		if results[i].DocID, err = s.docID(hash); err != nil {
+			return nil, nil, err
+		}
 	}
-	return results, rows.Err()
+	return results, opts.newPage("fts", query, offset, len(results), hasMore), nil
 }
 
 // Get retrieves a document by collection and path
@@ -386,59 +585,173 @@ func (s *Store) Get(collection, path string) (*Document, string, error) {
 		return nil, "", err
 	}
 	doc.Active = active == 1
+	if doc.DocID, err = s.docID(doc.Hash); err != nil {
+		return nil, "", err
+	}
+	doc.Context, err = s.ContextFor(doc.Collection, doc.Path)
+	if err != nil {
+		return nil, "", err
+	}
 	return &doc, content, nil
 }
 
 // MultiGetResult holds document with content
 type MultiGetResult struct {
-	Document *Document
-	Content  string
+	Document  *Document
+	Content   string
+	Truncated bool
+}
+
+// MultiGetResponse holds multi_get results and the requests that produced none
+type MultiGetResponse struct {
+	Results []MultiGetResult
+	Missing []string // references or patterns that matched no document
+	Skipped []string // documents left out once the byte budget was spent
 }
 
-// MultiGet retrieves multiple documents by paths
-func (s *Store) MultiGet(paths []string, maxBytes int) ([]MultiGetResult, error) {
+// MultiGet retrieves multiple documents by collection/path, #docid or glob
+// pattern, one per entry; use SplitRefs for a comma-separated list.
+// Documents are capped at maxLines lines each and truncated on line
+// boundaries to fit maxBytes.
+func (s *Store) MultiGet(paths []string, maxBytes, maxLines int) (*MultiGetResponse, error) {
 	if maxBytes <= 0 {
 		maxBytes = 10 * 1024 // 10KB default
 	}
 
-	var results []MultiGetResult
+	resp := &MultiGetResponse{}
+	seen := make(map[string]bool)
 	totalBytes := 0
 
-	for _, p := range paths {
-		// Parse collection/path format
-		collection, docPath := splitPath(p)
-		if collection == "" {
+	add := func(doc *Document, content string) {
+		key := doc.Collection + "/" + doc.Path
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		truncated := false
+		if maxLines > 0 {
+			sl := SliceLines(content, 1, maxLines)
+			truncated = sl.ToLine < sl.TotalLines
+			content = sl.Content
+		}
+
+		remaining := maxBytes - totalBytes
+		if remaining <= 0 {
+			resp.Skipped = append(resp.Skipped, key)
+			return
+		}
+		totalBytes += len(content)
+		if len(content) > remaining {
+			// The budget is spent: later documents are skipped
+			content = truncateContent(content, remaining)
+			truncated = true
+		}
+
+		if truncated {
+			content += "\n... (truncated)"
+		}
+		resp.Results = append(resp.Results, MultiGetResult{
+			Document:  doc,
+			Content:   content,
+			Truncated: truncated,
+		})
+	}
+
+	for _, ref := range paths {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
 			continue
 		}
 
-		doc, content, err := s.Get(collection, docPath)
-		if err != nil {
-			continue // Skip missing documents
+		if !isGlob(ref) {
+			// Accept collection/path or #docid
+			doc, content, err := s.GetByRef(ref)
+			if err != nil {
+				resp.Missing = append(resp.Missing, ref)
+				continue
+			}
+			add(doc, content)
+			continue
 		}
 
-		contentLen := len(content)
-		if totalBytes+contentLen > maxBytes {
-			// Truncate content to fit
-			remaining := maxBytes - totalBytes
-			if remaining > 0 {
-				content = content[:remaining] + "\n... (truncated)"
-			} else {
-				break
+		matches, err := s.matchDocuments(ref)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			resp.Missing = append(resp.Missing, ref)
+		}
+		for _, m := range matches {
+			doc, content, err := s.Get(m.Collection, m.Path)
+			if err != nil {
+				resp.Missing = append(resp.Missing, m.Collection+"/"+m.Path)
+				continue
 			}
+			add(doc, content)
 		}
+	}
 
-		results = append(results, MultiGetResult{
-			Document: doc,
-			Content:  content,
-		})
-		totalBytes += contentLen
+	return resp, nil
+}
 
-		if totalBytes >= maxBytes {
-			break
+// SplitRefs splits a comma-separated list of document references. A
+// comma inside a path is written as "\,".
+func SplitRefs(list string) []string {
+	var refs []string
+	var cur strings.Builder
+	for i := 0; i < len(list); i++ {
+		switch {
+		case list[i] == '\\' && i+1 < len(list) && list[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case list[i] == ',':
+			refs = append(refs, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(list[i])
 		}
 	}
+	return append(refs, strings.TrimSpace(cur.String()))
+}
 
-	return results, nil
+// matchDocuments returns the active documents whose collection/path matches a glob pattern
+func (s *Store) matchDocuments(pattern string) ([]Document, error) {
+	rows, err := s.db.Query(
+		`SELECT collection, path FROM documents WHERE active = 1 ORDER BY collection, path`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var d Document
+		if err := rows.Scan(&d.Collection, &d.Path); err != nil {
+			return nil, err
+		}
+		if matchPathGlob(pattern, d.Collection+"/"+d.Path) {
+			docs = append(docs, d)
+		}
+	}
+	return docs, rows.Err()
+}
+
+// truncateContent cuts content to at most n bytes, preferring the last
+// line boundary and never splitting a UTF-8 rune
+func truncateContent(content string, n int) string {
+	if len(content) <= n {
+		return content
+	}
+	for n > 0 && !utf8.RuneStart(content[n]) {
+		n--
+	}
+	cut := content[:n]
+	if idx := strings.LastIndexByte(cut, '\n'); idx > 0 {
+		cut = cut[:idx]
+	}
+	return cut
 }
 
 func splitPath(p string) (collection, path string) {
@@ -1,8 +1,10 @@
 package store
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -90,6 +92,35 @@ func TestCollectionCRUD(t *testing.T) {
 	}
 }
 
+func TestConcurrentWriters(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+
+	// Each handle stands in for a process, e.g. watch and an MCP tool
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		s, err := OpenPath(dbPath)
+		if err != nil {
+			t.Fatalf("OpenPath failed: %v", err)
+		}
+		defer s.Close()
+		go func(w int) {
+			for i := 0; i < 100; i++ {
+				path := fmt.Sprintf("w%d/%d.md", w, i)
+				if err := s.IndexDocument("docs", path, "Doc", path, fmt.Sprintf("%d%03d", w, i)); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}(w)
+	}
+	for w := 0; w < 4; w++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent IndexDocument failed: %v", err)
+		}
+	}
+}
+
 func TestIndexAndSearch(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.sqlite")
@@ -127,3 +158,379 @@ func TestIndexAndSearch(t *testing.T) {
 		t.Errorf("Content mismatch")
 	}
 }
+
+func TestResolveDocID(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.AddCollection("docs", tmpDir, "")
+	s.IndexDocument("docs", "a.md", "A", "alpha", "abc123ff")
+	s.IndexDocument("docs", "b.md", "B", "beta", "abc456ff")
+
+	doc, content, err := s.GetByRef("#abc123")
+	if err != nil {
+		t.Fatalf("GetByRef failed: %v", err)
+	}
+	if doc.Path != "a.md" || content != "alpha" || doc.DocID != "#abc123" {
+		t.Errorf("GetByRef = %s %q %s", doc.Path, content, doc.DocID)
+	}
+
+	if _, _, err := s.GetByRef("#abc"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected ambiguous docid error, got %v", err)
+	}
+	if _, _, err := s.GetByRef("#fff"); err == nil {
+		t.Error("expected error for unknown docid")
+	}
+
+	// Identical files share a docid, which still resolves
+	s.IndexDocument("docs", "copy/b.md", "B", "beta", "abc456ff")
+	doc, content, err = s.GetByRef("#abc456")
+	if err != nil {
+		t.Fatalf("GetByRef of duplicated content failed: %v", err)
+	}
+	if doc.Path != "b.md" || content != "beta" {
+		t.Errorf("GetByRef = %s %q, want b.md", doc.Path, content)
+	}
+
+	// Docids are case-insensitive
+	if !IsDocID("#ABC123") {
+		t.Error("IsDocID(#ABC123) = false")
+	}
+	if doc, _, err := s.GetByRef("#ABC123"); err != nil || doc.Path != "a.md" {
+		t.Errorf("GetByRef(#ABC123) = %v, %v", doc, err)
+	}
+
+	// Contents sharing the first 6 characters get longer, unique docids
+	s.IndexDocument("docs", "c.md", "C", "gamma alpha", "abc123ee")
+	doc, _, err = s.Get("docs", "a.md")
+	if err != nil || doc.DocID != "#abc123f" {
+		t.Fatalf("Get a.md = %v, %v, want docid #abc123f", doc, err)
+	}
+	results, err := s.Search("alpha", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	for _, r := range results {
+		found, _, err := s.GetByRef(r.DocID)
+		if err != nil || found.Path != r.Path {
+			t.Errorf("GetByRef(%s) for %s = %v, %v", r.DocID, r.Path, found, err)
+		}
+	}
+	if len(results) != 2 {
+		t.Errorf("Search = %d results, want 2", len(results))
+	}
+
+	// Renaming a document with unchanged content keeps its docid
+	if err := s.RemoveCollection("docs"); err != nil {
+		t.Fatalf("RemoveCollection failed: %v", err)
+	}
+	s.IndexDocument("docs", "moved/a.md", "A", "alpha", "abc123ff")
+	doc, _, err = s.GetByRef("#abc123")
+	if err != nil {
+		t.Fatalf("GetByRef after move failed: %v", err)
+	}
+	if doc.Path != "moved/a.md" {
+		t.Errorf("Path = %q, want moved/a.md", doc.Path)
+	}
+}
+
+func TestGetWithLine(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.AddCollection("notes", t.TempDir(), "")
+	s.IndexDocument("notes", "a.md", "A", "alpha", "aaa111")
+	s.IndexDocument("notes", "meeting:2024", "Meeting", "agenda", "bbb222")
+
+	tests := []struct {
+		ref  string
+		path string
+		line int
+	}{
+		{"notes/a.md", "a.md", 0},
+		{"notes/a.md:12", "a.md", 12},
+		{"notes/meeting:2024", "meeting:2024", 0},
+		{"notes/meeting:2024:3", "meeting:2024", 3},
+		{"#aaa111:5", "a.md", 5},
+	}
+	for _, tt := range tests {
+		doc, _, line, err := GetWithLine(tt.ref, s.GetByRef)
+		if err != nil {
+			t.Errorf("GetWithLine(%q): %v", tt.ref, err)
+			continue
+		}
+		if doc.Path != tt.path || line != tt.line {
+			t.Errorf("GetWithLine(%q) = %s, %d, want %s, %d", tt.ref, doc.Path, line, tt.path, tt.line)
+		}
+	}
+
+	if _, _, _, err := GetWithLine("notes/missing.md:4", s.GetByRef); err == nil {
+		t.Error("expected error for missing document")
+	}
+}
+
+func TestMultiGet(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.IndexDocument("notes", "2026/01/a.md", "A", "line one\nline two\n", "aaa111")
+	s.IndexDocument("notes", "2026/b.md", "B", "héllo wörld\nsecond line\n", "bbb222")
+	s.IndexDocument("notes", "2025/c.md", "C", "old", "ccc333")
+
+	resp, err := s.MultiGet(SplitRefs("notes/2026/**/*.md, notes/missing.md"), 0, 0)
+	if err != nil {
+		t.Fatalf("MultiGet failed: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Errorf("MultiGet results = %d, want 2", len(resp.Results))
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "notes/missing.md" {
+		t.Errorf("Missing = %v", resp.Missing)
+	}
+
+	// Budget ends inside the second document: truncated on a line boundary
+	resp, err = s.MultiGet([]string{"notes/2026/b.md", "notes/2026/01/a.md", "notes/2025/c.md"}, 36, 0)
+	if err != nil {
+		t.Fatalf("MultiGet failed: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("MultiGet results = %d, want 2", len(resp.Results))
+	}
+	if r := resp.Results[1]; !r.Truncated || r.Content != "line one\n... (truncated)" {
+		t.Errorf("second result = %q, truncated %v", r.Content, r.Truncated)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0] != "notes/2025/c.md" {
+		t.Errorf("Skipped = %v", resp.Skipped)
+	}
+
+	// Cutting in the middle of a rune backs off to the rune start
+	if got := truncateContent("héllo", 2); got != "h" {
+		t.Errorf("truncateContent = %q, want %q", got, "h")
+	}
+
+	resp, _ = s.MultiGet([]string{"notes/2026/01/a.md"}, 0, 1)
+	if resp.Results[0].Content != "line one\n... (truncated)" {
+		t.Errorf("max_lines content = %q", resp.Results[0].Content)
+	}
+
+	// Paths may contain commas, escaped in a list
+	s.IndexDocument("notes", "a, b.md", "AB", "commas", "ddd444")
+	for _, refs := range [][]string{{"notes/a, b.md"}, {"notes/a*b.md"}, SplitRefs(`notes/a\, b.md`)} {
+		resp, err = s.MultiGet(refs, 0, 0)
+		if err != nil || len(resp.Results) != 1 || resp.Results[0].Content != "commas" {
+			t.Errorf("MultiGet(%q) = %+v, %v", refs, resp, err)
+		}
+	}
+}
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"notes/**/*.md", "notes/a.md", true},
+		{"notes/**/*.md", "notes/2026/01/a.md", true},
+		{"notes/2026/*.md", "notes/2026/01/a.md", false},
+		{"*/a.md", "docs/a.md", true},
+		{"notes/**", "notes/x/y.txt", true},
+	}
+	for _, tt := range tests {
+		if got := matchPathGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPathGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathContexts(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.AddContext("missing", "", "nope"); err == nil {
+		t.Error("expected error for unknown collection")
+	}
+
+	s.AddCollection("notes", tmpDir, "")
+	s.AddContext("notes", "", "Team notes")
+	s.AddContext("notes", "/infra/", "Meeting notes from the infra team")
+	s.AddContext("notes", "infrastructure", "Not a parent of infra")
+	s.IndexDocument("notes", "infra/2026/standup.md", "Standup", "kubernetes upgrade", "aaa111")
+
+	results, err := s.Search("kubernetes", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	want := "Team notes\nMeeting notes from the infra team"
+	if len(results) != 1 || results[0].Context != want {
+		t.Errorf("Search context = %q, want %q", results[0].Context, want)
+	}
+
+	doc, _, err := s.Get("notes", "infra/2026/standup.md")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if doc.Context != want {
+		t.Errorf("Get context = %q, want %q", doc.Context, want)
+	}
+
+	if err := s.RemoveContext("notes", "infra"); err != nil {
+		t.Fatalf("RemoveContext failed: %v", err)
+	}
+	contexts, _ := s.ListContexts()
+	if len(contexts) != 2 {
+		t.Errorf("ListContexts = %d, want 2", len(contexts))
+	}
+}
+
+func TestSearchMinScoreAndExplain(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.IndexDocument("docs", "strong.md", "Kubernetes upgrade", "kubernetes kubernetes kubernetes", "aaa111")
+	s.IndexDocument("docs", "weak.md", "Notes", "a long note that mentions kubernetes once among many other words here", "bbb222")
+	for i := 0; i < 5; i++ {
+		s.IndexDocument("docs", fmt.Sprintf("other%d.md", i), "Other", "unrelated", fmt.Sprintf("ccc%03d", i))
+	}
+
+	results, _, err := s.SearchWithOptions("kubernetes", SearchOptions{Explain: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search results = %d, want 2", len(results))
+	}
+	for i, r := range results {
+		if r.Score <= 0 || r.Score >= 1 {
+			t.Errorf("Score = %f, want within (0, 1)", r.Score)
+		}
+		if r.Explanation == nil || r.Explanation.Rank != i+1 {
+			t.Fatalf("Explanation = %+v", r.Explanation)
+		}
+	}
+	if ex := results[0].Explanation; ex.TitleBM25 >= 0 || ex.FilepathBM25 != 0 {
+		t.Errorf("column scores = %+v", ex)
+	}
+
+	// A threshold between the two scores keeps only the strong match
+	threshold := (results[0].Score + results[1].Score) / 2
+	results, _, err = s.SearchWithOptions("kubernetes", SearchOptions{MinScore: threshold})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "strong.md" {
+		t.Errorf("Search with min score = %+v", results)
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	// Identical documents tie on score, so order falls back to doc id
+	for i := 0; i < 5; i++ {
+		s.IndexDocument("docs", fmt.Sprintf("doc%d.md", i), "Doc", "same text", fmt.Sprintf("aaa%03d", i))
+	}
+
+	var paths []string
+	opts := SearchOptions{Limit: 2}
+	for pages := 0; ; pages++ {
+		results, page, err := s.SearchWithOptions("same", opts)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		if !page.HasMore {
+			break
+		}
+		if pages > 5 {
+			t.Fatal("paging did not terminate")
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	want := []string{"doc0.md", "doc1.md", "doc2.md", "doc3.md", "doc4.md"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("paged paths = %v, want %v", paths, want)
+	}
+
+	results, page, _ := s.SearchWithOptions("same", SearchOptions{Limit: 2, Offset: 4})
+	if len(results) != 1 || page.HasMore {
+		t.Errorf("last page = %d results, has more %v", len(results), page.HasMore)
+	}
+
+	if _, _, err := s.SearchWithOptions("other", SearchOptions{Cursor: opts.Cursor}); err == nil {
+		t.Error("expected error reusing a cursor for another query")
+	}
+	if _, _, err := s.SearchWithOptions("same", SearchOptions{Cursor: opts.Cursor, MinScore: 0.1}); err == nil {
+		t.Error("expected error reusing a cursor with another min score")
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("GQMD_DB", "")
+
+	tests := []struct {
+		name   string
+		dbPath string
+		index  string
+		env    string
+		want   string
+	}{
+		{"default", "", "", "", filepath.Join(cache, "gqmd", "index.sqlite")},
+		{"named index", "", "work", "", filepath.Join(cache, "gqmd", "work.sqlite")},
+		{"explicit path", "/tmp/x.sqlite", "", "", "/tmp/x.sqlite"},
+		{"env", "", "", "/tmp/env.sqlite", "/tmp/env.sqlite"},
+		{"flag beats env", "", "work", "/tmp/env.sqlite", filepath.Join(cache, "gqmd", "work.sqlite")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GQMD_DB", tt.env)
+			got, err := ResolvePath(tt.dbPath, tt.index)
+			if err != nil {
+				t.Fatalf("ResolvePath: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, index := range []string{"../etc", "a/b", "work.sqlite"} {
+		if _, err := ResolvePath("", index); err == nil {
+			t.Errorf("ResolvePath(%q) should fail", index)
+		}
+	}
+	if _, err := ResolvePath("/tmp/x.sqlite", "work"); err == nil {
+		t.Error("ResolvePath with both path and index should fail")
+	}
+}
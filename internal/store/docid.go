@@ -0,0 +1,146 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// docIDLength is the minimum number of hash characters in a short docid
+const docIDLength = 6
+
+// DocID returns the short "#abc123" document id for a content hash.
+// It depends only on content, so it survives renames and moves. Ids shown
+// for indexed documents come from Store.docID, which lengthens them where
+// two contents share a prefix.
+func DocID(hash string) string {
+	if len(hash) > docIDLength {
+		hash = hash[:docIDLength]
+	}
+	return "#" + hash
+}
+
+// docID returns the shortest docid, at least docIDLength characters, that
+// no other active content shares, so ids printed in results resolve. Only
+// the neighbouring hashes in sort order need checking.
+func (s *Store) docID(hash string) (string, error) {
+	n := docIDLength
+	for _, query := range []string{
+		`SELECT MAX(hash) FROM documents WHERE hash < ? AND active = 1`,
+		`SELECT MIN(hash) FROM documents WHERE hash > ? AND active = 1`,
+	} {
+		var other sql.NullString
+		if err := s.db.QueryRow(query, hash).Scan(&other); err != nil {
+			return "", err
+		}
+		if l := commonPrefixLen(hash, other.String) + 1; l > n {
+			n = l
+		}
+	}
+	if n > len(hash) {
+		n = len(hash)
+	}
+	return "#" + hash[:n], nil
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// IsDocID reports whether ref looks like a short docid ("#abc123"),
+// in either case
+func IsDocID(ref string) bool {
+	id := strings.TrimPrefix(ref, "#")
+	if len(id) == len(ref) || id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveDocID finds the active document whose content hash starts with
+// the given docid. Documents with identical content share a docid, so the
+// first of them by collection and path is returned. Prefixes matching
+// more than one distinct content are an error listing the candidates.
+func (s *Store) ResolveDocID(id string) (*Document, string, error) {
+	prefix := strings.ToLower(strings.TrimPrefix(id, "#"))
+	if prefix == "" {
+		return nil, "", fmt.Errorf("empty docid")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT hash, MIN(collection || '/' || path) FROM documents
+		WHERE hash >= ? AND hash < ? AND active = 1
+		GROUP BY hash
+		ORDER BY 2
+		LIMIT 6`,
+		prefix, prefix+"g",
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	// One match per distinct content
+	var matches []string
+	for rows.Next() {
+		var hash, ref string
+		if err := rows.Scan(&hash, &ref); err != nil {
+			return nil, "", err
+		}
+		matches = append(matches, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, "", fmt.Errorf("docid %s: %w", id, sql.ErrNoRows)
+	case 1:
+		col, path := splitPath(matches[0])
+		return s.Get(col, path)
+	default:
+		return nil, "", fmt.Errorf("docid %s is ambiguous, matches %s", id, strings.Join(matches, ", "))
+	}
+}
+
+// GetByRef retrieves a document by docid ("#abc123") or "collection/path"
+func (s *Store) GetByRef(ref string) (*Document, string, error) {
+	if IsDocID(ref) {
+		return s.ResolveDocID(ref)
+	}
+	collection, path := splitPath(ref)
+	if collection == "" {
+		return nil, "", fmt.Errorf("invalid document reference %q, want collection/path or #docid", ref)
+	}
+	return s.Get(collection, path)
+}
+
+// GetWithLine looks up a reference that may end in a ":line" suffix with
+// get. The literal reference is tried first, so a file whose name ends in
+// ":<digits>" stays reachable; the suffix is only split off when that
+// lookup fails. line is 0 when no suffix was used.
+func GetWithLine(ref string, get func(ref string) (*Document, string, error)) (doc *Document, content string, line int, err error) {
+	doc, content, err = get(ref)
+	if err == nil {
+		return doc, content, 0, nil
+	}
+	path, line := ParsePathLine(ref)
+	if line == 0 {
+		return nil, "", 0, err
+	}
+	doc, content, err = get(path)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return doc, content, line, nil
+}
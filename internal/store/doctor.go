@@ -0,0 +1,254 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Finding is the outcome of one doctor check
+type Finding struct {
+	Check   string
+	Problem string // empty if the check passed
+	Fix     string // what to do about the problem
+	Note    string // information that needs no fixing
+	fix     func() error
+}
+
+// OK reports whether the check passed
+func (f *Finding) OK() bool {
+	return f.Problem == ""
+}
+
+// Fixable reports whether Apply can repair the problem
+func (f *Finding) Fixable() bool {
+	return f.fix != nil
+}
+
+// Apply repairs the problem
+func (f *Finding) Apply() error {
+	if f.fix == nil {
+		return fmt.Errorf("%s: no automatic fix", f.Check)
+	}
+	return f.fix()
+}
+
+// DiagnoseOptions configures Diagnose
+type DiagnoseOptions struct {
+	// Model is the embedding model in use; embeddings kept for other
+	// models are noted
+	Model string
+}
+
+// Diagnose checks the index for corruption and inconsistencies
+func (s *Store) Diagnose(opts DiagnoseOptions) ([]Finding, error) {
+	checks := []func() (Finding, error){
+		s.checkIntegrity,
+		s.checkFTS,
+		s.checkOrphans,
+		s.checkCollectionPaths,
+		func() (Finding, error) { return s.checkEmbeddings(opts.Model) },
+	}
+
+	var findings []Finding
+	for _, check := range checks {
+		f, err := check()
+		if err != nil {
+			return findings, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func (s *Store) checkIntegrity() (Finding, error) {
+	f := Finding{Check: "integrity"}
+
+	rows, err := s.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return f, err
+	}
+	var msgs []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return f, err
+		}
+		if msg != "ok" {
+			msgs = append(msgs, msg)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return f, err
+	}
+
+	if _, err := s.db.Exec(`INSERT INTO documents_fts(documents_fts) VALUES('integrity-check')`); err != nil {
+		msgs = append(msgs, "full-text index: "+err.Error())
+	}
+
+	if len(msgs) > 0 {
+		f.Problem = strings.Join(msgs, "; ")
+		f.Fix = "the database file is damaged: export what you can, then delete it and rescan"
+	}
+	return f, nil
+}
+
+func (s *Store) checkFTS() (Finding, error) {
+	f := Finding{Check: "fts"}
+
+	var missing, stale int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM documents d
+		WHERE NOT EXISTS (SELECT 1 FROM documents_fts f WHERE f.rowid = d.id)`).Scan(&missing)
+	if err != nil {
+		return f, err
+	}
+	err = s.db.QueryRow(`
+		SELECT COUNT(*) FROM documents_fts
+		WHERE rowid NOT IN (SELECT id FROM documents)`).Scan(&stale)
+	if err != nil {
+		return f, err
+	}
+
+	if missing > 0 || stale > 0 {
+		f.Problem = fmt.Sprintf("%d documents missing from the full-text index, %d entries without a document", missing, stale)
+		f.Fix = "rebuild the full-text index from stored content"
+		f.fix = s.rebuildFTS
+	}
+	return f, nil
+}
+
+// rebuildFTS repopulates the full-text index from documents and content
+func (s *Store) rebuildFTS() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM documents_fts`); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO documents_fts (rowid, filepath, title, body)
+		SELECT d.id, d.collection || '/' || d.path, d.title, c.doc
+		FROM documents d JOIN content c ON c.hash = d.hash`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) checkOrphans() (Finding, error) {
+	f := Finding{Check: "orphans"}
+
+	var content, embeddings, chunks int
+	err := s.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM content WHERE hash NOT IN (SELECT hash FROM documents)),
+			(SELECT COUNT(*) FROM embeddings WHERE hash NOT IN (SELECT hash FROM documents)),
+			(SELECT COUNT(*) FROM chunks WHERE hash NOT IN (SELECT hash FROM documents))`,
+	).Scan(&content, &embeddings, &chunks)
+	if err != nil {
+		return f, err
+	}
+
+	if content+embeddings+chunks > 0 {
+		f.Problem = fmt.Sprintf("%d content rows, %d embeddings and %d chunks belong to no document", content, embeddings, chunks)
+		f.Fix = "delete them (or run gqmd cleanup)"
+		f.fix = func() error { return s.removeOrphans(&CleanupResult{}) }
+	}
+	return f, nil
+}
+
+func (s *Store) checkCollectionPaths() (Finding, error) {
+	f := Finding{Check: "collections"}
+
+	cols, err := s.ListCollections()
+	if err != nil {
+		return f, err
+	}
+	var problems []string
+	for _, c := range cols {
+		info, err := os.Stat(c.Path)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %s does not exist", c.Name, c.Path))
+		case !info.IsDir():
+			problems = append(problems, fmt.Sprintf("%s: %s is not a directory", c.Name, c.Path))
+		}
+	}
+
+	if len(problems) > 0 {
+		f.Problem = strings.Join(problems, "; ")
+		f.Fix = "restore the directory, or gqmd remove the collection and add it again at its new path"
+	}
+	return f, nil
+}
+
+// checkEmbeddings reports models whose embeddings have more than one
+// dimension, which cannot be compared with each other. Keeping several
+// models is fine, as embeddings are stored and searched per model.
+func (s *Store) checkEmbeddings(model string) (Finding, error) {
+	f := Finding{Check: "embeddings"}
+
+	rows, err := s.db.Query(`
+		SELECT model, dimensions, COUNT(*) FROM embeddings
+		GROUP BY model, dimensions ORDER BY model, COUNT(*) DESC, dimensions`)
+	if err != nil {
+		return f, err
+	}
+	type group struct {
+		model string
+		dims  int
+		count int
+	}
+	var groups []group
+	for rows.Next() {
+		var g group
+		if err := rows.Scan(&g.model, &g.dims, &g.count); err != nil {
+			rows.Close()
+			return f, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return f, err
+	}
+
+	// Keep the largest group of each model
+	keep := make(map[string]int)
+	var others, mixed []string
+	for _, g := range groups {
+		dims, seen := keep[g.model]
+		if !seen {
+			keep[g.model] = g.dims
+			if g.model != model {
+				others = append(others, fmt.Sprintf("%s (%d dims): %d", g.model, g.dims, g.count))
+			}
+			continue
+		}
+		mixed = append(mixed, fmt.Sprintf("%s has %d embeddings of %d dims besides %d", g.model, g.count, g.dims, dims))
+	}
+	if len(others) > 0 {
+		f.Note = "other models: " + strings.Join(others, ", ")
+	}
+	if len(mixed) == 0 {
+		return f, nil
+	}
+
+	f.Problem = "embeddings mix dimensions: " + strings.Join(mixed, "; ")
+	f.Fix = "delete the embeddings of the other dimensions, then run gqmd embed"
+	f.fix = func() error {
+		for m, dims := range keep {
+			if _, err := s.db.Exec(`DELETE FROM embeddings WHERE model = ? AND dimensions != ?`, m, dims); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return f, nil
+}
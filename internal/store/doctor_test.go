@@ -0,0 +1,86 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func findingsByCheck(t *testing.T, s *Store, model string) map[string]Finding {
+	t.Helper()
+	findings, err := s.Diagnose(DiagnoseOptions{Model: model})
+	if err != nil {
+		t.Fatalf("Diagnose: %v", err)
+	}
+	m := make(map[string]Finding)
+	for _, f := range findings {
+		m[f.Check] = f
+	}
+	return m
+}
+
+func TestDiagnose(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenPath(filepath.Join(dir, "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.AddCollection("notes", dir, "**/*.md")
+	s.AddCollection("gone", filepath.Join(dir, "missing"), "**/*.md")
+	s.IndexDocument("notes", "a.md", "A", "alpha body", "h1")
+	s.IndexDocument("notes", "b.md", "B", "beta body", "h2")
+
+	healthy := findingsByCheck(t, s, "m")
+	for _, check := range []string{"integrity", "fts", "orphans", "embeddings"} {
+		if f := healthy[check]; !f.OK() {
+			t.Errorf("%s on a healthy index: %s", check, f.Problem)
+		}
+	}
+	if f := healthy["collections"]; f.OK() || f.Fixable() {
+		t.Errorf("collections = %+v, want an unfixable problem", f)
+	}
+
+	// Break the index the ways doctor looks for
+	s.db.Exec(`DELETE FROM documents_fts WHERE rowid = (SELECT id FROM documents WHERE path = 'a.md')`)
+	s.db.Exec(`INSERT INTO documents_fts (rowid, filepath, title, body) VALUES (999, 'x.md', 'X', 'stale')`)
+	s.StoreEmbedding("h1", 0, "m", Vector{1, 0})
+	s.StoreEmbedding("h2", 0, "m", Vector{1, 0, 0})
+	s.StoreEmbedding("h2", 0, "old", Vector{1, 0, 0})
+	s.StoreEmbedding("orphan", 0, "m", Vector{1, 0})
+
+	broken := findingsByCheck(t, s, "m")
+	for _, check := range []string{"fts", "orphans", "embeddings"} {
+		f := broken[check]
+		if f.OK() || !f.Fixable() {
+			t.Fatalf("%s = %+v, want a fixable problem", check, f)
+		}
+		if err := f.Apply(); err != nil {
+			t.Fatalf("fix %s: %v", check, err)
+		}
+	}
+
+	fixed := findingsByCheck(t, s, "m")
+	for _, check := range []string{"integrity", "fts", "orphans", "embeddings"} {
+		if f := fixed[check]; !f.OK() {
+			t.Errorf("%s after fix: %s", check, f.Problem)
+		}
+	}
+	if results, _ := s.Search("alpha", 10); len(results) != 1 {
+		t.Errorf("rebuilt FTS: Search(alpha) = %d results, want 1", len(results))
+	}
+	if results, _ := s.Search("stale", 10); len(results) != 0 {
+		t.Errorf("stale FTS row still searchable")
+	}
+	// Only the minority dimension of m is deleted, other models are kept
+	if n := countRows(t, s, "embeddings"); n != 2 {
+		t.Errorf("embeddings = %d, want h1 for m and h2 for old", n)
+	}
+	if f := fixed["embeddings"]; !strings.Contains(f.Note, "old (3 dims)") {
+		t.Errorf("embeddings note = %q, want the old model listed", f.Note)
+	}
+	if f := healthy["embeddings"]; f.Note != "" {
+		t.Errorf("embeddings note on a healthy index = %q", f.Note)
+	}
+}
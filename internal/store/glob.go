@@ -0,0 +1,40 @@
+package store
+
+import (
+	"path"
+	"strings"
+)
+
+// isGlob reports whether a reference contains glob metacharacters
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// matchPathGlob matches a slash-separated path against a glob pattern.
+// A "**" segment matches zero or more path segments; other segments
+// follow path.Match.
+func matchPathGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
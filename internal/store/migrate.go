@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration upgrades the schema by one version. The version a database is
+// at is kept in PRAGMA user_version; migration i brings it to i+1.
+type migration struct {
+	name string
+	up   func(tx *sql.Tx) error
+}
+
+// migrations is applied in order and must only ever be appended to.
+// Databases created before versioning report version 0 but may already
+// have some of these tables, so the early steps use IF NOT EXISTS.
+var migrations = []migration{
+	{"baseline schema", migrateBaseline},
+	{"chunk text for embeddings", migrateExec(`
+	CREATE TABLE IF NOT EXISTS chunks (
+		hash TEXT NOT NULL,
+		chunk_idx INTEGER NOT NULL,
+		from_line INTEGER NOT NULL,
+		to_line INTEGER NOT NULL,
+		breadcrumb TEXT NOT NULL DEFAULT '',
+		text TEXT NOT NULL,
+		PRIMARY KEY (hash, chunk_idx)
+	)`)},
+	{"path contexts", migrateExec(`
+	CREATE TABLE IF NOT EXISTS contexts (
+		collection TEXT NOT NULL,
+		path TEXT NOT NULL DEFAULT '',
+		context TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (collection, path)
+	)`)},
+	{"collection scan times", migrateExec(
+		`ALTER TABLE collections ADD COLUMN last_scan_at TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE collections ADD COLUMN last_scan_ms INTEGER NOT NULL DEFAULT 0`,
+	)},
+	{"collection file size limits", migrateExec(
+		`ALTER TABLE collections ADD COLUMN max_file_size INTEGER NOT NULL DEFAULT 0`,
+	)},
+	// SQLite cannot change a primary key, so the table is rebuilt
+	{"embeddings keyed by model", migrateExec(
+		`CREATE TABLE embeddings_by_model (
+			hash TEXT NOT NULL,
+			chunk_idx INTEGER NOT NULL DEFAULT 0,
+			model TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			vector BLOB NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (hash, model, chunk_idx)
+		)`,
+		`INSERT INTO embeddings_by_model (hash, chunk_idx, model, dimensions, vector, created_at)
+			SELECT hash, chunk_idx, model, dimensions, vector, created_at FROM embeddings`,
+		`DROP TABLE embeddings`,
+		`ALTER TABLE embeddings_by_model RENAME TO embeddings`,
+	)},
+}
+
+// SchemaVersion is the schema version this build creates and expects
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrateExec returns a migration step running statements in order
+func migrateExec(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func migrateBaseline(tx *sql.Tx) error {
+	return migrateExec(
+		// Collections table
+		`CREATE TABLE IF NOT EXISTS collections (
+			id INTEGER PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			path TEXT NOT NULL,
+			pattern TEXT DEFAULT '**/*.md',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Content-addressable storage
+		`CREATE TABLE IF NOT EXISTS content (
+			hash TEXT PRIMARY KEY,
+			doc TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			collection TEXT NOT NULL,
+			path TEXT NOT NULL,
+			title TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			modified_at TEXT NOT NULL,
+			active INTEGER NOT NULL DEFAULT 1,
+			FOREIGN KEY (hash) REFERENCES content(hash) ON DELETE CASCADE,
+			UNIQUE(collection, path)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_documents_collection ON documents(collection, active)`,
+		`CREATE INDEX IF NOT EXISTS idx_documents_hash ON documents(hash)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
+			filepath, title, body,
+			tokenize='porter unicode61'
+		)`,
+		// Vector embeddings table
+		`CREATE TABLE IF NOT EXISTS embeddings (
+			hash TEXT NOT NULL,
+			chunk_idx INTEGER NOT NULL DEFAULT 0,
+			model TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			vector BLOB NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (hash, chunk_idx)
+		)`,
+	)(tx)
+}
+
+// SchemaVersion returns the schema version recorded in the database
+func (s *Store) SchemaVersion() (int, error) {
+	var v int
+	err := s.db.QueryRow(`PRAGMA user_version`).Scan(&v)
+	return v, err
+}
+
+// migrate applies pending migrations, each in its own transaction so a
+// failed step leaves the database at the previous version
+func (s *Store) migrate() error {
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current == len(migrations) {
+		return nil
+	}
+
+	for {
+		done, err := s.migrateStep()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// migrateStep applies the migration after the database's version and
+// reports whether there was none left. It takes the write lock before
+// reading the version, so processes opening an old database at the same
+// time apply each step once.
+func (s *Store) migrateStep() (bool, error) {
+	// Serializable transactions begin IMMEDIATE with this driver
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var v int
+	if err := tx.QueryRow(`PRAGMA user_version`).Scan(&v); err != nil {
+		return false, err
+	}
+	if v > len(migrations) {
+		return false, fmt.Errorf("database schema version %d is newer than this gqmd supports (%d)", v, len(migrations))
+	}
+	if v == len(migrations) {
+		return true, nil
+	}
+
+	m := migrations[v]
+	if err := m.up(tx); err != nil {
+		return false, fmt.Errorf("migration %d (%s): %w", v+1, m.name, err)
+	}
+	// PRAGMA does not take bound parameters
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, v+1)); err != nil {
+		return false, fmt.Errorf("migration %d (%s): %w", v+1, m.name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("migration %d (%s): %w", v+1, m.name, err)
+	}
+	return false, nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openFixture creates a database from a SQL script in testdata
+func openFixture(t *testing.T, name string) string {
+	t.Helper()
+	script, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "index.sqlite")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return dbPath
+}
+
+func tableExists(t *testing.T, s *Store, name string) bool {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?`, name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	dbPath := openFixture(t, "baseline.sql")
+
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatalf("OpenPath: %v", err)
+	}
+	defer s.Close()
+
+	v, err := s.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != SchemaVersion() {
+		t.Errorf("version = %d, want %d", v, SchemaVersion())
+	}
+	for _, table := range []string{"chunks", "contexts"} {
+		if !tableExists(t, s, table) {
+			t.Errorf("table %s not created", table)
+		}
+	}
+
+	// Existing data survives and works with the new tables
+	results, err := s.Search("goroutines", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "go.md" {
+		t.Fatalf("Search = %+v", results)
+	}
+	if err := s.AddContext("notes", "", "Programming notes"); err != nil {
+		t.Fatalf("AddContext: %v", err)
+	}
+	vec, err := s.VectorSearch(Vector{1, 0}, 10)
+	if err != nil {
+		t.Fatalf("VectorSearch: %v", err)
+	}
+	if len(vec) != 1 || vec[0].Context != "Programming notes" {
+		t.Errorf("VectorSearch = %+v", vec)
+	}
+}
+
+func TestMigrateIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.sqlite")
+	for i := 0; i < 2; i++ {
+		s, err := OpenPath(dbPath)
+		if err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		v, _ := s.SchemaVersion()
+		if v != SchemaVersion() {
+			t.Errorf("open %d: version = %d", i, v)
+		}
+		s.Close()
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.sqlite")
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.db.Exec(`PRAGMA user_version = 999`)
+	s.Close()
+
+	if _, err := OpenPath(dbPath); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("OpenPath = %v, want newer version error", err)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+
+	dbPath := openFixture(t, "baseline.sql")
+	addColumn := migration{"add column", migrateExec(`ALTER TABLE documents ADD COLUMN mtime INTEGER`)}
+	migrations = append(append([]migration{}, saved...), addColumn,
+		migration{"broken", func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE half_done (id INTEGER)`); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}},
+	)
+
+	if _, err := OpenPath(dbPath); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("OpenPath = %v, want migration error", err)
+	}
+
+	// The failed step is rolled back, earlier steps are kept
+	migrations = append(append([]migration{}, saved...), addColumn)
+	s, err := OpenPath(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	v, _ := s.SchemaVersion()
+	if v != len(saved)+1 {
+		t.Errorf("version = %d, want %d", v, len(saved)+1)
+	}
+	if tableExists(t, s, "half_done") {
+		t.Error("failed migration was not rolled back")
+	}
+	if _, err := s.db.Exec(`UPDATE documents SET mtime = 1`); err != nil {
+		t.Errorf("column from earlier migration missing: %v", err)
+	}
+}
+
+func TestMigrateConcurrentOpen(t *testing.T) {
+	dbPath := openFixture(t, "baseline.sql")
+
+	// Processes opening an old database at once, e.g. serve and a CLI call
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			s, err := OpenPath(dbPath)
+			if err == nil {
+				s.Close()
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent OpenPath: %v", err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package store
+
+import "github.com/NOTAschool/gqmd/internal/extract"
+
+// OutlineEntry is a heading with the extent of its section. A section runs
+// until the next heading of the same or a higher level, so it includes
+// its subsections.
+type OutlineEntry struct {
+	Heading
+	EndLine int
+	Bytes   int
+}
+
+// BuildOutline returns the heading tree of a markdown document in document order
+func BuildOutline(content string) []OutlineEntry {
+	lines := extract.SplitLines(content)
+	headings := ParseHeadings(content)
+
+	// Byte offset of the start of each line, plus one past the last line
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+
+	entries := make([]OutlineEntry, 0, len(headings))
+	for i, h := range headings {
+		end := len(lines)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line - 1
+				break
+			}
+		}
+		entries = append(entries, OutlineEntry{
+			Heading: h,
+			EndLine: end,
+			Bytes:   offsets[end] - offsets[h.Line-1],
+		})
+	}
+	return entries
+}
@@ -2,76 +2,477 @@ package store
 
 import (
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/extract"
 )
 
-// ScanResult holds scan statistics
+// DefaultMaxFileSize is the largest file a scan indexes unless the
+// collection sets its own limit
+const DefaultMaxFileSize = 10 << 20
+
+// ScanResult reports what a scan did to each file of a collection
 type ScanResult struct {
-	Added   int
-	Updated int
-	Removed int
-	Errors  int
+	Collection     string        `json:"collection"`
+	Added          int           `json:"added"`
+	Updated        int           `json:"updated"`
+	Unchanged      int           `json:"unchanged"`
+	Removed        int           `json:"removed"`
+	Skipped        []SkippedFile `json:"skipped"`
+	Errors         []ScanError   `json:"errors"`
+	DurationMillis int64         `json:"duration_ms"`
+}
+
+// String summarizes the result on one line
+func (r *ScanResult) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d unchanged, %d removed, %d skipped, %d errors in %s",
+		r.Added, r.Updated, r.Unchanged, r.Removed, len(r.Skipped), len(r.Errors),
+		time.Duration(r.DurationMillis)*time.Millisecond)
+}
+
+// SkippedFile is a file a scan left out, and why
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Stages of a scan at which a file can fail. StageScan is a failure of
+// the whole collection, with no path.
+const (
+	StageScan    = "scan"
+	StageWalk    = "walk"
+	StageRead    = "read"
+	StageDecode  = "decode"
+	StageExtract = "extract"
+	StageIndex   = "index"
+	StageRemove  = "remove"
+)
+
+// ScanError is a file or directory a scan failed on
+type ScanError struct {
+	Path  string
+	Stage string
+	Err   error
+}
+
+func (e *ScanError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Path, e.Stage, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON writes the error as {path, stage, error}
+func (e ScanError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path  string `json:"path"`
+		Stage string `json:"stage"`
+		Error string `json:"error"`
+	}{e.Path, e.Stage, e.Err.Error()})
+}
+
+// SkipError reports a file that is deliberately not indexed, because it
+// is too large or not text
+type SkipError struct {
+	Path   string
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("%s: skipped: %s", e.Path, e.Reason)
+}
+
+// ProgressFunc reports how far a long-running operation has got.
+// Total is 0 when unknown.
+type ProgressFunc func(done, total int, message string)
+
+func (fn ProgressFunc) report(done, total int, message string) {
+	if fn != nil {
+		fn(done, total, message)
+	}
+}
+
+// DefaultScanBatch is how many documents a scan writes per transaction
+const DefaultScanBatch = 500
+
+// ScanOptions configures a collection scan
+type ScanOptions struct {
+	Progress ProgressFunc
+	// Workers is how many goroutines walk, read and hash files; the
+	// default is the number of CPUs
+	Workers int
+	// BatchSize is how many documents are committed per transaction
+	BatchSize int
 }
 
 // ScanCollection scans a collection directory and indexes documents
 func (s *Store) ScanCollection(name string) (*ScanResult, error) {
+	return s.ScanCollectionWithOptions(name, ScanOptions{})
+}
+
+// scannedFile is a file read and hashed by a scan worker
+type scannedFile struct {
+	relPath string
+	text    string
+	hash    string
+	title   string
+	err     error
+}
+
+// ScanCollectionWithOptions scans a collection directory and indexes
+// documents, reporting progress per file. Files are found and read by
+// parallel workers; a single writer commits them in batches.
+func (s *Store) ScanCollectionWithOptions(name string, opts ScanOptions) (*ScanResult, error) {
 	col, err := s.GetCollection(name)
 	if err != nil {
 		return nil, err
 	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultScanBatch
+	}
 
-	result := &ScanResult{}
+	start := time.Now()
+	result := &ScanResult{Collection: name, Skipped: []SkippedFile{}, Errors: []ScanError{}}
 
-	// Walk directory and index files
-	err = filepath.Walk(col.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			result.Errors++
-			return nil
+	// Collect matching files first so progress has a total
+	files, walkErrors := walkCollection(col, workers)
+	result.Errors = append(result.Errors, walkErrors...)
+
+	// Read and hash in parallel, in file order. The window bounds how far
+	// readers run ahead of the writer so large trees are not held in memory.
+	paths := make(chan int)
+	window := make(chan struct{}, workers*4)
+	done := make(chan struct{})
+	defer close(done)
+	scanned := make([]chan scannedFile, len(files))
+	for i := range scanned {
+		scanned[i] = make(chan scannedFile, 1)
+	}
+	go func() {
+		defer close(paths)
+		for i := range files {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			paths <- i
 		}
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range paths {
+				scanned[i] <- readFile(col, files[i])
+			}
+		}()
+	}
+
+	if err := s.writeScanned(col, scanned, window, batchSize, result, opts.Progress); err != nil {
+		return result, err
+	}
+	if err := s.removeMissing(col, files, walkErrors, result); err != nil {
+		return result, err
+	}
+
+	// Edited and removed files leave their old content behind
+	if err := s.removeOrphans(&CleanupResult{}); err != nil {
+		return result, fmt.Errorf("cleanup: %w", err)
+	}
 
-		if info.IsDir() {
-			return nil
+	result.DurationMillis = time.Since(start).Milliseconds()
+	_, err = s.db.Exec(`UPDATE collections SET last_scan_at = ?, last_scan_ms = ? WHERE name = ?`,
+		nowISO(), result.DurationMillis, name)
+	return result, err
+}
+
+// removeMissing deletes the documents of files that no longer exist or
+// no longer match the pattern. Documents under directories the walk could
+// not read are kept.
+func (s *Store) removeMissing(col *Collection, files []string, walkErrors []ScanError, result *ScanResult) error {
+	found := make(map[string]bool, len(files))
+	for _, f := range files {
+		found[f] = true
+	}
+
+	rows, err := s.db.Query(`SELECT path FROM documents WHERE collection = ?`, col.Name)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return err
+		}
+		if !found[path] && !underFailedDir(path, walkErrors) {
+			missing = append(missing, path)
 		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
 
-		// Get relative path
-		relPath, err := filepath.Rel(col.Path, path)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, path := range missing {
+		n, err := removeDocumentsTx(tx, `collection = ? AND path = ?`, col.Name, path)
 		if err != nil {
-			result.Errors++
-			return nil
+			result.Errors = append(result.Errors, ScanError{Path: path, Stage: StageRemove, Err: err})
+			continue
 		}
+		result.Removed += n
+	}
+	return tx.Commit()
+}
 
-		// Check if matches pattern (simple glob matching)
-		if !matchGlob(col.Pattern, relPath) {
-			return nil
+func underFailedDir(path string, walkErrors []ScanError) bool {
+	for _, e := range walkErrors {
+		if e.Path == "." || strings.HasPrefix(path, e.Path+"/") {
+			return true
 		}
+	}
+	return false
+}
 
-		// Read file content
-		content, err := os.ReadFile(path)
+// writeScanned indexes scanned files in order, committing every batchSize
+// documents and freeing a window slot for each file taken
+func (s *Store) writeScanned(col *Collection, scanned []chan scannedFile, window chan struct{}, batchSize int, result *ScanResult, progress ProgressFunc) error {
+	var tx *sql.Tx
+	pending := 0
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	now := nowISO()
+	for i, ch := range scanned {
+		f := <-ch
+		<-window
+		progress.report(i+1, len(scanned), f.relPath)
+		var skip *SkipError
+		if f.err != nil && !errors.As(f.err, &skip) {
+			result.Errors = append(result.Errors, asScanError(f.relPath, f.err))
+			continue
+		}
+
+		if tx == nil {
+			var err error
+			if tx, err = s.db.Begin(); err != nil {
+				return err
+			}
+		}
+		if skip != nil {
+			// Drop what an earlier scan indexed for the file
+			result.Skipped = append(result.Skipped, SkippedFile{Path: f.relPath, Reason: skip.Reason})
+			if _, err := removeDocumentsTx(tx, `collection = ? AND path = ?`, col.Name, f.relPath); err != nil {
+				result.Errors = append(result.Errors, ScanError{Path: f.relPath, Stage: StageRemove, Err: err})
+			}
+		} else {
+			change, err := indexDocumentTx(tx, col.Name, f.relPath, f.title, f.text, f.hash, now)
+			if err != nil {
+				result.Errors = append(result.Errors, ScanError{Path: f.relPath, Stage: StageIndex, Err: err})
+				continue
+			}
+			switch change {
+			case docAdded:
+				result.Added++
+			case docUpdated:
+				result.Updated++
+			default:
+				result.Unchanged++
+			}
+		}
+
+		if pending++; pending >= batchSize {
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			tx, pending = nil, 0
+		}
+	}
+
+	if tx != nil {
+		err := tx.Commit()
+		tx = nil
+		return err
+	}
+	return nil
+}
+
+// walkCollection lists the files matching a collection's pattern, walking
+// directories in parallel. Paths are relative and sorted.
+func walkCollection(col *Collection, workers int) ([]string, []ScanError) {
+	var (
+		mu    sync.Mutex
+		files []string
+		errs  []ScanError
+		wg    sync.WaitGroup
+	)
+	sem := make(chan struct{}, workers)
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		defer wg.Done()
+		sem <- struct{}{}
+		entries, err := os.ReadDir(dir)
+		<-sem
+
+		mu.Lock()
+		defer mu.Unlock()
 		if err != nil {
-			result.Errors++
-			return nil
+			rel, _ := filepath.Rel(col.Path, dir)
+			errs = append(errs, ScanError{Path: filepath.ToSlash(rel), Stage: StageWalk, Err: err})
+			return
 		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if e.IsDir() {
+				wg.Add(1)
+				go walk(path)
+				continue
+			}
+			relPath, err := filepath.Rel(col.Path, path)
+			if err != nil {
+				errs = append(errs, ScanError{Path: path, Stage: StageWalk, Err: err})
+				continue
+			}
+			if matchGlob(col.Pattern, relPath) {
+				files = append(files, relPath)
+			}
+		}
+	}
 
-		// Calculate hash
-		hash := hashContent(content)
+	wg.Add(1)
+	walk(col.Path)
+	wg.Wait()
 
-		// Extract title from first line
-		title := extractTitle(string(content), relPath)
+	sort.Strings(files)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return files, errs
+}
+
+// readFile reads, extracts and hashes one file of a collection
+func readFile(col *Collection, relPath string) scannedFile {
+	doc, err := readDocument(col, relPath)
+	if err != nil {
+		return scannedFile{relPath: relPath, err: err}
+	}
+	return scannedFile{
+		relPath: relPath,
+		text:    doc.Text,
+		hash:    hashContent([]byte(doc.Text)),
+		title:   doc.Title,
+	}
+}
 
-		// Index document
-		if err := s.IndexDocument(name, relPath, title, string(content), hash); err != nil {
-			result.Errors++
-			return nil
+// readDocument reads a file, converts it to UTF-8 and extracts it with
+// the extractor registered for its extension. Files over the collection's
+// size limit and binary files are reported as a *SkipError.
+func readDocument(col *Collection, relPath string) (*extract.Document, error) {
+	f, err := os.Open(filepath.Join(col.Path, relPath))
+	if err != nil {
+		return nil, &ScanError{Path: relPath, Stage: StageRead, Err: err}
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, &ScanError{Path: relPath, Stage: StageRead, Err: err}
+	}
+	if limit := col.maxFileSize(); limit > 0 && info.Size() > limit {
+		return nil, &SkipError{Path: relPath, Reason: fmt.Sprintf("too large (%s, limit %s)",
+			FormatBytes(info.Size()), FormatBytes(limit))}
+	}
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &ScanError{Path: relPath, Stage: StageRead, Err: err}
+	}
+	content, _, err = extract.Decode(content)
+	if errors.Is(err, extract.ErrBinary) {
+		return nil, &SkipError{Path: relPath, Reason: "binary"}
+	}
+	if err != nil {
+		return nil, &ScanError{Path: relPath, Stage: StageDecode, Err: err}
+	}
+
+	doc, err := extract.Extract(relPath, content)
+	if err != nil {
+		return nil, &ScanError{Path: relPath, Stage: StageExtract, Err: err}
+	}
+	return doc, nil
+}
+
+// IndexFile reads and indexes one file of a collection. A file that is
+// skipped loses any earlier index entry and is reported as a *SkipError.
+func (s *Store) IndexFile(col *Collection, relPath string) error {
+	doc, err := readDocument(col, relPath)
+	var skip *SkipError
+	if errors.As(err, &skip) {
+		if _, rerr := s.removeDocuments(`collection = ? AND path = ?`, col.Name, relPath); rerr != nil {
+			return rerr
 		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return s.IndexDocument(col.Name, relPath, doc.Title, doc.Text, hashContent([]byte(doc.Text)))
+}
 
-		result.Added++
-		return nil
-	})
+// asScanError records an error at the stage it reports, if any
+func asScanError(path string, err error) ScanError {
+	var se *ScanError
+	if errors.As(err, &se) {
+		return *se
+	}
+	return ScanError{Path: path, Stage: StageRead, Err: err}
+}
 
-	return result, err
+// maxFileSize is the collection's effective size limit, or 0 for none
+func (c *Collection) maxFileSize() int64 {
+	switch {
+	case c.MaxFileSize == 0:
+		return DefaultMaxFileSize
+	case c.MaxFileSize < 0:
+		return 0
+	}
+	return c.MaxFileSize
+}
+
+// Matches reports whether a path relative to the collection root matches its pattern
+func (c *Collection) Matches(relPath string) bool {
+	return matchGlob(c.Pattern, relPath)
 }
 
 func hashContent(content []byte) string {
@@ -79,21 +480,19 @@ func hashContent(content []byte) string {
 	return hex.EncodeToString(h[:])
 }
 
-func extractTitle(content, fallback string) string {
-	lines := strings.SplitN(content, "\n", 3)
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "# ") {
-			return strings.TrimPrefix(line, "# ")
+// matchGlob matches a path against a glob pattern
+// Supports **/*.md style patterns, and alternatives such as
+// **/*.{md,org,rst} for collections of several formats
+func matchGlob(pattern, path string) bool {
+	if alts := expandBraces(pattern); len(alts) > 1 {
+		for _, alt := range alts {
+			if matchGlob(alt, path) {
+				return true
+			}
 		}
+		return false
 	}
-	// Use filename without extension as fallback
-	return strings.TrimSuffix(filepath.Base(fallback), filepath.Ext(fallback))
-}
 
-// matchGlob matches a path against a glob pattern
-// Supports **/*.md style patterns
-func matchGlob(pattern, path string) bool {
 	// Handle **/*.ext pattern
 	if strings.HasPrefix(pattern, "**/") {
 		ext := strings.TrimPrefix(pattern, "**/")
@@ -113,3 +512,23 @@ func matchGlob(pattern, path string) bool {
 	matched, _ := filepath.Match(pattern, filepath.Base(path))
 	return matched
 }
+
+// expandBraces expands the alternatives of a pattern, e.g. "*.{md,txt}"
+// into "*.md" and "*.txt"
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[start:], '}')
+	if end < 0 {
+		return []string{pattern}
+	}
+	end += start
+
+	var out []string
+	for _, alt := range strings.Split(pattern[start+1:end], ",") {
+		out = append(out, expandBraces(pattern[:start]+alt+pattern[end+1:])...)
+	}
+	return out
+}
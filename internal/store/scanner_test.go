@@ -0,0 +1,436 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestScanCollectionProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	docs := filepath.Join(tmpDir, "docs")
+	os.MkdirAll(filepath.Join(docs, "sub"), 0755)
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# A\nalpha"), 0644)
+	os.WriteFile(filepath.Join(docs, "sub", "b.md"), []byte("# B\nbeta"), 0644)
+	os.WriteFile(filepath.Join(docs, "c.txt"), []byte("ignored"), 0644)
+	s.AddCollection("docs", docs, "")
+
+	var calls [][2]int
+	result, err := s.ScanCollectionWithOptions("docs", ScanOptions{
+		Progress: func(done, total int, message string) {
+			calls = append(calls, [2]int{done, total})
+		},
+	})
+	if err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+	if result.Added != 2 {
+		t.Errorf("Added = %d, want 2", result.Added)
+	}
+	if len(calls) != 2 || calls[1] != [2]int{2, 2} {
+		t.Errorf("progress calls = %v", calls)
+	}
+}
+
+func TestScanCollectionBatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	docs := filepath.Join(tmpDir, "docs")
+	writeTree(t, docs, 25)
+	s.AddCollection("docs", docs, "")
+
+	opts := ScanOptions{Workers: 3, BatchSize: 4}
+	for i := 0; i < 2; i++ {
+		result, err := s.ScanCollectionWithOptions("docs", opts)
+		if err != nil {
+			t.Fatalf("scan %d failed: %v", i, err)
+		}
+		// The second scan finds every file already indexed
+		added, unchanged := 25, 0
+		if i == 1 {
+			added, unchanged = 0, 25
+		}
+		if result.Added != added || result.Unchanged != unchanged || len(result.Errors) != 0 {
+			t.Errorf("scan %d: Added = %d, Unchanged = %d, Errors = %v", i, result.Added, result.Unchanged, result.Errors)
+		}
+	}
+
+	var docsCount, ftsCount int
+	s.db.QueryRow(`SELECT COUNT(*) FROM documents WHERE active = 1`).Scan(&docsCount)
+	s.db.QueryRow(`SELECT COUNT(*) FROM documents_fts`).Scan(&ftsCount)
+	if docsCount != 25 || ftsCount != 25 {
+		t.Errorf("documents = %d, fts rows = %d, want 25", docsCount, ftsCount)
+	}
+}
+
+func TestScanCollectionFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	docs := filepath.Join(tmpDir, "docs")
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# Markdown\nalpha"), 0644)
+	os.WriteFile(filepath.Join(docs, "b.org"), []byte("#+TITLE: Org Notes\n* Tasks\nbeta"), 0644)
+	os.WriteFile(filepath.Join(docs, "c.html"), []byte("<title>Page</title><h1>Intro</h1><p>gamma</p>"), 0644)
+	os.WriteFile(filepath.Join(docs, "d.ipynb"), []byte(`{"cells": [
+		{"cell_type": "markdown", "source": "# Analysis"},
+		{"cell_type": "code", "source": "print(1)", "outputs": [{"text": "1"}]}]}`), 0644)
+	os.WriteFile(filepath.Join(docs, "e.pdf"), []byte("ignored"), 0644)
+	s.AddCollection("docs", docs, "**/*.{md,org,html,ipynb}")
+
+	result, err := s.ScanCollection("docs")
+	if err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+	if result.Added != 4 {
+		t.Errorf("Added = %d, want 4", result.Added)
+	}
+
+	want := map[string]string{"a.md": "Markdown", "b.org": "Org Notes", "c.html": "Page", "d.ipynb": "Analysis"}
+	for path, title := range want {
+		doc, content, err := s.Get("docs", path)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", path, err)
+		}
+		if doc.Title != title {
+			t.Errorf("%s: title = %q, want %q", path, doc.Title, title)
+		}
+		if strings.Contains(content, "<") || strings.Contains(content, "#+") {
+			t.Errorf("%s: content not extracted: %q", path, content)
+		}
+	}
+
+	// Sections of other formats are markdown headings once extracted
+	_, content, _ := s.Get("docs", "c.html")
+	if outline := BuildOutline(content); len(outline) != 1 || outline[0].Text != "Intro" {
+		t.Errorf("outline of c.html = %+v", outline)
+	}
+
+	// Notebook cells are chunked on their own
+	_, content, _ = s.Get("docs", "d.ipynb")
+	chunks := ChunkContent(content, 0)
+	if len(chunks) != 2 || chunks[1].Breadcrumb != "Analysis > Code cell 1" {
+		t.Errorf("chunks of d.ipynb = %+v", chunks)
+	}
+}
+
+func TestScanCollectionSkips(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("# 笔记\n中文内容\n"))
+	docs := filepath.Join(tmpDir, "docs")
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(docs, "big.md"), []byte("# Big\n"+strings.Repeat("x", 200)), 0644)
+	os.WriteFile(filepath.Join(docs, "image.md"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644)
+	os.WriteFile(filepath.Join(docs, "gbk.md"), gbk, 0644)
+	s.AddCollection("docs", docs, "")
+
+	// Indexed while under the default limit
+	if _, err := s.ScanCollection("docs"); err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+	if _, _, err := s.Get("docs", "big.md"); err != nil {
+		t.Fatalf("big.md not indexed: %v", err)
+	}
+
+	if err := s.SetMaxFileSize("docs", 100); err != nil {
+		t.Fatalf("SetMaxFileSize failed: %v", err)
+	}
+	result, err := s.ScanCollection("docs")
+	if err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+	if result.Unchanged != 1 || len(result.Errors) != 0 {
+		t.Errorf("Unchanged = %d, Errors = %v, want 1, none", result.Unchanged, result.Errors)
+	}
+	want := []SkippedFile{
+		{Path: "big.md", Reason: "too large (206 B, limit 100 B)"},
+		{Path: "image.md", Reason: "binary"},
+	}
+	if len(result.Skipped) != len(want) {
+		t.Fatalf("Skipped = %+v, want %+v", result.Skipped, want)
+	}
+	for i := range want {
+		if result.Skipped[i] != want[i] {
+			t.Errorf("Skipped[%d] = %+v, want %+v", i, result.Skipped[i], want[i])
+		}
+	}
+
+	// A file skipped now is no longer searchable
+	if _, _, err := s.Get("docs", "big.md"); err == nil {
+		t.Error("big.md still indexed after being skipped")
+	}
+
+	doc, content, err := s.Get("docs", "gbk.md")
+	if err != nil {
+		t.Fatalf("Get(gbk.md) failed: %v", err)
+	}
+	if doc.Title != "笔记" || !strings.Contains(content, "中文内容") {
+		t.Errorf("gbk.md = %q, %q, want transcoded text", doc.Title, content)
+	}
+}
+
+func TestScanCollectionCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	docs := filepath.Join(tmpDir, "docs")
+	os.MkdirAll(docs, 0755)
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		os.WriteFile(filepath.Join(docs, name), []byte("# "+name+"\n"), 0644)
+	}
+	s.AddCollection("docs", docs, "**/*.{md,ipynb}")
+	if _, err := s.ScanCollection("docs"); err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# a.md\nchanged\n"), 0644)
+	os.Remove(filepath.Join(docs, "b.md"))
+	os.WriteFile(filepath.Join(docs, "d.md"), []byte("# d.md\n"), 0644)
+	os.WriteFile(filepath.Join(docs, "broken.ipynb"), []byte(`{"cells": [`), 0644)
+
+	result, err := s.ScanCollection("docs")
+	if err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+	if result.Added != 1 || result.Updated != 1 || result.Unchanged != 1 || result.Removed != 1 {
+		t.Errorf("result = %s, want 1 added, 1 updated, 1 unchanged, 1 removed", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Path != "broken.ipynb" || result.Errors[0].Stage != StageExtract {
+		t.Fatalf("Errors = %v, want an extract error for broken.ipynb", result.Errors)
+	}
+	if _, _, err := s.Get("docs", "b.md"); err == nil {
+		t.Error("b.md still indexed after being removed")
+	}
+}
+
+// writeTree writes n markdown files spread over nested directories
+func writeTree(tb testing.TB, root string, n int) {
+	tb.Helper()
+	for i := 0; i < n; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%02d", i%50), fmt.Sprintf("e%02d", i/50%20))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		body := fmt.Sprintf("# Note %d\n\nSome text about topic %d and topic %d.\n", i, i%97, i%13)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("n%05d.md", i)), []byte(body), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// serialScan is a copy of ScanCollection as it was before the pipeline,
+// kept as the benchmark baseline: each file is read with os.ReadFile and
+// committed with its own IndexDocument call from the walk callback. Only
+// its error counting is adapted to today's ScanResult.
+func serialScan(s *Store, name string) (*ScanResult, error) {
+	col, err := s.GetCollection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScanResult{}
+
+	// Walk directory and index files
+	err = filepath.Walk(col.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			result.Errors = append(result.Errors, ScanError{Path: path, Stage: StageWalk, Err: err})
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		// Get relative path
+		relPath, err := filepath.Rel(col.Path, path)
+		if err != nil {
+			result.Errors = append(result.Errors, ScanError{Path: path, Stage: StageWalk, Err: err})
+			return nil
+		}
+
+		// Check if matches pattern (simple glob matching)
+		if !matchGlob(col.Pattern, relPath) {
+			return nil
+		}
+
+		// Read file content
+		content, err := os.ReadFile(path)
+		if err != nil {
+			result.Errors = append(result.Errors, ScanError{Path: relPath, Stage: StageRead, Err: err})
+			return nil
+		}
+
+		// Calculate hash
+		hash := hashContent(content)
+
+		// Extract title from first line
+		title := serialTitle(string(content), relPath)
+
+		// Index document
+		if err := s.IndexDocument(name, relPath, title, string(content), hash); err != nil {
+			result.Errors = append(result.Errors, ScanError{Path: relPath, Stage: StageIndex, Err: err})
+			return nil
+		}
+
+		result.Added++
+		return nil
+	})
+
+	return result, err
+}
+
+// serialTitle is the title extraction serialScan used
+func serialTitle(content, fallback string) string {
+	lines := strings.SplitN(content, "\n", 3)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimPrefix(line, "# ")
+		}
+	}
+	// Use filename without extension as fallback
+	return strings.TrimSuffix(filepath.Base(fallback), filepath.Ext(fallback))
+}
+
+// BenchmarkScanCollection compares the pipelined scan with serialScan, the
+// loop it replaced, on a 50k-file tree:
+//
+//	go test ./internal/store -run XXX -bench ScanCollection -benchtime 1x
+func BenchmarkScanCollection(b *testing.B) {
+	const files = 50000
+	docs := filepath.Join(b.TempDir(), "docs")
+	writeTree(b, docs, files)
+
+	cases := []struct {
+		name string
+		scan func(s *Store) (*ScanResult, error)
+	}{
+		{"serial", func(s *Store) (*ScanResult, error) { return serialScan(s, "docs") }},
+		{"pipelined", func(s *Store) (*ScanResult, error) { return s.ScanCollection("docs") }},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				s, err := OpenPath(filepath.Join(b.TempDir(), "bench.sqlite"))
+				if err != nil {
+					b.Fatal(err)
+				}
+				s.AddCollection("docs", docs, "")
+				b.StartTimer()
+
+				result, err := c.scan(s)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if result.Added != files {
+					b.Fatalf("Added = %d, want %d", result.Added, files)
+				}
+
+				b.StopTimer()
+				s.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestEmbedDocuments(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.IndexDocument("docs", "a.md", "A", "alpha", "aaa111")
+	s.IndexDocument("docs", "b.md", "B", "beta", "bbb222")
+
+	fake := func(text string) ([]float32, error) {
+		return []float32{float32(len(text)), 1}, nil
+	}
+	var last int
+	result, err := s.EmbedDocuments("fake", fake, EmbedOptions{
+		Progress: func(done, total int, message string) { last = done },
+	})
+	if err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+	if result.Embedded != 2 || last != 2 {
+		t.Errorf("Embedded = %d, last progress = %d", result.Embedded, last)
+	}
+
+	// Already embedded documents are not pending any more
+	pending, _ := s.PendingEmbeddings("fake", "")
+	if len(pending) != 0 {
+		t.Errorf("PendingEmbeddings = %v, want none", pending)
+	}
+
+	// A document the service rejects is counted and the run goes on,
+	// even when it is the first
+	rejecting := func(text string) ([]float32, error) {
+		if text == "alpha" {
+			return nil, errors.New("input too long")
+		}
+		return []float32{1, 1}, nil
+	}
+	result, err = s.EmbedDocuments("other", rejecting, EmbedOptions{})
+	if err != nil || result.Embedded != 1 || result.Errors != 1 {
+		t.Errorf("EmbedDocuments = %+v, %v, want 1 embedded, 1 error", result, err)
+	}
+
+	// An unreachable service stops the run wherever it goes down
+	s.IndexDocument("docs", "c.md", "C", "gamma", "ccc333")
+	calls := 0
+	unreachable := func(string) ([]float32, error) {
+		calls++
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	if _, err := s.EmbedDocuments("other", unreachable, EmbedOptions{}); err == nil || calls != 1 {
+		t.Errorf("EmbedDocuments = %v after %d calls, want an error after 1", err, calls)
+	}
+
+	// So does a service failing every document, as for an unknown model
+	for i := 0; i < 2*maxEmbedFailures; i++ {
+		s.IndexDocument("docs", fmt.Sprintf("many/%d.md", i), "N", fmt.Sprintf("doc %d", i), fmt.Sprintf("ddd%03d", i))
+	}
+	calls = 0
+	unknownModel := func(string) ([]float32, error) {
+		calls++
+		return nil, errors.New(`model "typo" not found`)
+	}
+	result, err = s.EmbedDocuments("typo", unknownModel, EmbedOptions{})
+	if err == nil || !strings.Contains(err.Error(), "not found") || calls != maxEmbedFailures || result.Errors != maxEmbedFailures {
+		t.Errorf("EmbedDocuments = %+v, %v after %d calls, want an error after %d", result, err, calls, maxEmbedFailures)
+	}
+}
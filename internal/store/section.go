@@ -0,0 +1,141 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/extract"
+)
+
+// Heading is a markdown ATX heading found in a document
+type Heading = extract.Heading
+
+// Slice is a line range of a document
+type Slice struct {
+	Content    string
+	FromLine   int // 1-based, inclusive
+	ToLine     int // 1-based, inclusive
+	TotalLines int
+}
+
+// SliceOptions selects part of a document. The zero value selects everything.
+type SliceOptions struct {
+	FromLine int
+	MaxLines int
+	Section  string
+}
+
+// IsZero reports whether the options select the whole document
+func (o SliceOptions) IsZero() bool {
+	return o.FromLine <= 1 && o.MaxLines <= 0 && o.Section == ""
+}
+
+// SliceContent applies the options to content. A section takes
+// precedence over the line range, and MaxLines caps the section length.
+func SliceContent(content string, opts SliceOptions) (Slice, error) {
+	if opts.Section == "" {
+		return SliceLines(content, opts.FromLine, opts.MaxLines), nil
+	}
+	sl, err := SliceSection(content, opts.Section)
+	if err != nil {
+		return Slice{}, err
+	}
+	if opts.MaxLines > 0 && sl.ToLine-sl.FromLine+1 > opts.MaxLines {
+		sl = SliceLines(content, sl.FromLine, opts.MaxLines)
+	}
+	return sl, nil
+}
+
+// ParseHeadings returns the ATX headings of a markdown document,
+// ignoring lines inside fenced code blocks
+func ParseHeadings(content string) []Heading {
+	return extract.ParseHeadings(content)
+}
+
+// SliceLines returns up to maxLines lines starting at fromLine (1-based).
+// A fromLine <= 0 starts at the first line, a maxLines <= 0 reads to the end.
+func SliceLines(content string, fromLine, maxLines int) Slice {
+	lines := extract.SplitLines(content)
+	total := len(lines)
+
+	if fromLine <= 0 {
+		fromLine = 1
+	}
+	if fromLine > total {
+		return Slice{FromLine: fromLine, ToLine: fromLine - 1, TotalLines: total}
+	}
+
+	end := total
+	if maxLines > 0 && fromLine-1+maxLines < end {
+		end = fromLine - 1 + maxLines
+	}
+
+	return Slice{
+		Content:    strings.Join(lines[fromLine-1:end], "\n"),
+		FromLine:   fromLine,
+		ToLine:     end,
+		TotalLines: total,
+	}
+}
+
+// SliceSection returns the content under the heading matching section,
+// up to the next heading of the same or a higher level
+func SliceSection(content, section string) (Slice, error) {
+	want := normalizeHeading(section)
+	headings := ParseHeadings(content)
+
+	for i, h := range headings {
+		if normalizeHeading(h.Text) != want {
+			continue
+		}
+		end := len(extract.SplitLines(content))
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line - 1
+				break
+			}
+		}
+		return SliceLines(content, h.Line, end-h.Line+1), nil
+	}
+	return Slice{}, fmt.Errorf("section %q not found", section)
+}
+
+func normalizeHeading(s string) string {
+	s = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s), "#"))
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Numbered returns the slice content with each line prefixed by its line number
+func (sl Slice) Numbered() string {
+	if sl.Content == "" {
+		return ""
+	}
+	lines := strings.Split(sl.Content, "\n")
+	width := len(strconv.Itoa(sl.ToLine))
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%*d: %s", width, sl.FromLine+i, line)
+	}
+	return b.String()
+}
+
+// ParsePathLine splits a "path:123" reference into path and line number.
+// Paths without a numeric suffix are returned unchanged with line 0. Use
+// GetWithLine to look references up, as a file name may itself end in
+// ":<digits>".
+func ParsePathLine(p string) (string, int) {
+	idx := strings.LastIndex(p, ":")
+	if idx < 0 || idx == len(p)-1 {
+		return p, 0
+	}
+	line, err := strconv.Atoi(p[idx+1:])
+	if err != nil || line <= 0 {
+		return p, 0
+	}
+	return p[:idx], line
+}
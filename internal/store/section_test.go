@@ -0,0 +1,107 @@
+package store
+
+import "testing"
+
+const sectionDoc = `# Title
+intro
+## Setup
+install
+` + "```sh\n# not a heading\n```" + `
+### Details
+more
+## Usage
+run it
+`
+
+func TestParseHeadings(t *testing.T) {
+	headings := ParseHeadings(sectionDoc)
+	want := []Heading{
+		{Level: 1, Text: "Title", Line: 1},
+		{Level: 2, Text: "Setup", Line: 3},
+		{Level: 3, Text: "Details", Line: 8},
+		{Level: 2, Text: "Usage", Line: 10},
+	}
+	if len(headings) != len(want) {
+		t.Fatalf("ParseHeadings = %d headings, want %d", len(headings), len(want))
+	}
+	for i := range want {
+		if headings[i] != want[i] {
+			t.Errorf("heading %d = %+v, want %+v", i, headings[i], want[i])
+		}
+	}
+}
+
+func TestSliceContent(t *testing.T) {
+	sl, err := SliceContent(sectionDoc, SliceOptions{Section: "## setup"})
+	if err != nil {
+		t.Fatalf("SliceContent failed: %v", err)
+	}
+	if sl.FromLine != 3 || sl.ToLine != 9 {
+		t.Errorf("section lines = %d-%d, want 3-9", sl.FromLine, sl.ToLine)
+	}
+
+	sl, err = SliceContent(sectionDoc, SliceOptions{FromLine: 10, MaxLines: 1})
+	if err != nil {
+		t.Fatalf("SliceContent failed: %v", err)
+	}
+	if sl.Content != "## Usage" || sl.TotalLines != 11 {
+		t.Errorf("slice = %q of %d lines", sl.Content, sl.TotalLines)
+	}
+	if got := sl.Numbered(); got != "10: ## Usage" {
+		t.Errorf("Numbered = %q", got)
+	}
+
+	if _, err := SliceContent(sectionDoc, SliceOptions{Section: "missing"}); err == nil {
+		t.Error("expected error for missing section")
+	}
+}
+
+func TestParsePathLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		path string
+		line int
+	}{
+		{"docs/a.md", "docs/a.md", 0},
+		{"docs/a.md:42", "docs/a.md", 42},
+		{"docs/a:b.md", "docs/a:b.md", 0},
+	}
+	for _, tt := range tests {
+		path, line := ParsePathLine(tt.in)
+		if path != tt.path || line != tt.line {
+			t.Errorf("ParsePathLine(%q) = %q, %d", tt.in, path, line)
+		}
+	}
+}
+
+func TestBuildOutline(t *testing.T) {
+	entries := BuildOutline(sectionDoc)
+	if len(entries) != 4 {
+		t.Fatalf("BuildOutline = %d entries, want 4", len(entries))
+	}
+	if entries[0].EndLine != 11 || entries[0].Bytes != len(sectionDoc) {
+		t.Errorf("title section = lines %d-%d, %d bytes", entries[0].Line, entries[0].EndLine, entries[0].Bytes)
+	}
+	if entries[2].Text != "Details" || entries[2].EndLine != 9 || entries[2].Bytes != len("### Details\nmore\n") {
+		t.Errorf("details section = %+v", entries[2])
+	}
+}
+
+func TestChunkContent(t *testing.T) {
+	chunks := ChunkContent("preamble\n"+sectionDoc, 0)
+	if len(chunks) != 5 {
+		t.Fatalf("ChunkContent = %d chunks, want 5", len(chunks))
+	}
+	if c := chunks[0]; c.Text != "preamble" || c.Breadcrumb != "" {
+		t.Errorf("preamble chunk = %+v", c)
+	}
+	if c := chunks[3]; c.Breadcrumb != "Title > Setup > Details" || c.FromLine != 9 || c.ToLine != 10 {
+		t.Errorf("details chunk = %+v", c)
+	}
+
+	// Oversized sections split on line boundaries
+	chunks = ChunkContent("# Big\naaaa\nbbbb\ncccc\n", 12)
+	if len(chunks) != 2 || chunks[1].FromLine != 3 || chunks[1].Breadcrumb != "Big" {
+		t.Errorf("split chunks = %+v", chunks)
+	}
+}
@@ -0,0 +1,284 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Status summarizes the index
+type Status struct {
+	DBPath          string             `json:"db_path"`
+	SchemaVersion   int                `json:"schema_version"`
+	TotalDocs       int                `json:"total_docs"`
+	Collections     int                `json:"collections"`
+	HasVectorIndex  bool               `json:"has_vector_index"`
+	ContentBytes    int64              `json:"content_bytes"`
+	DBBytes         int64              `json:"db_bytes"`
+	FTSBytes        int64              `json:"fts_bytes"`
+	Model           string             `json:"model,omitempty"`
+	NeedsEmbedding  int                `json:"needs_embedding"`
+	Oldest          *DocumentAge       `json:"oldest,omitempty"`
+	Newest          *DocumentAge       `json:"newest,omitempty"`
+	CollectionStats []CollectionStatus `json:"collection_stats"`
+	Embeddings      []ModelCoverage    `json:"embeddings"`
+}
+
+// CollectionStatus holds per-collection statistics
+type CollectionStatus struct {
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	Docs           int    `json:"docs"`
+	ContentBytes   int64  `json:"content_bytes"`
+	NeedsEmbedding int    `json:"needs_embedding"`
+	LastScanAt     string `json:"last_scan_at,omitempty"`
+	LastScanMillis int64  `json:"last_scan_ms"`
+}
+
+// ModelCoverage reports how much of the index a model has embedded
+type ModelCoverage struct {
+	Model      string  `json:"model"`
+	Dimensions int     `json:"dimensions"`
+	Docs       int     `json:"docs"`
+	Chunks     int     `json:"chunks"`
+	Coverage   float64 `json:"coverage"` // fraction of active documents
+}
+
+// DocumentAge identifies a document by when its content last changed
+type DocumentAge struct {
+	Collection string `json:"collection"`
+	Path       string `json:"path"`
+	ModifiedAt string `json:"modified_at"`
+}
+
+// StatusOptions configures GetStatusWithOptions
+type StatusOptions struct {
+	// Model counts documents needing embedding for this model; if empty,
+	// documents with no embedding at all are counted
+	Model string
+}
+
+// GetStatus returns index statistics
+func (s *Store) GetStatus() (*Status, error) {
+	return s.GetStatusWithOptions(StatusOptions{})
+}
+
+// GetStatusWithOptions returns index statistics, counting pending
+// embeddings for opts.Model
+func (s *Store) GetStatusWithOptions(opts StatusOptions) (*Status, error) {
+	status := &Status{DBPath: s.dbPath, Model: opts.Model, CollectionStats: []CollectionStatus{}}
+	var err error
+
+	if status.SchemaVersion, err = s.SchemaVersion(); err != nil {
+		return nil, err
+	}
+	if status.DBBytes, err = s.dbSize(); err != nil {
+		return nil, err
+	}
+	if status.FTSBytes, err = s.ftsSize(); err != nil {
+		return nil, err
+	}
+
+	// Embedding is pending when no embedding exists for the model, or for
+	// any model when none is given
+	pending := `NOT EXISTS (SELECT 1 FROM embeddings e WHERE e.hash = d.hash AND (? = '' OR e.model = ?))`
+
+	rows, err := s.db.Query(`
+		SELECT c.name, c.path, c.last_scan_at, c.last_scan_ms,
+			COUNT(d.id), COALESCE(SUM(length(CAST(ct.doc AS BLOB))), 0),
+			COALESCE(SUM(CASE WHEN d.id IS NOT NULL AND `+pending+` THEN 1 ELSE 0 END), 0)
+		FROM collections c
+		LEFT JOIN documents d ON d.collection = c.name AND d.active = 1
+		LEFT JOIN content ct ON ct.hash = d.hash
+		GROUP BY c.name ORDER BY c.name`,
+		opts.Model, opts.Model,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c CollectionStatus
+		if err := rows.Scan(&c.Name, &c.Path, &c.LastScanAt, &c.LastScanMillis,
+			&c.Docs, &c.ContentBytes, &c.NeedsEmbedding); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		status.CollectionStats = append(status.CollectionStats, c)
+		status.ContentBytes += c.ContentBytes
+		status.NeedsEmbedding += c.NeedsEmbedding
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	status.Collections = len(status.CollectionStats)
+
+	// Documents of removed collections are not counted above
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM documents WHERE active = 1`).Scan(&status.TotalDocs)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Embeddings, err = s.embeddingCoverage(status.TotalDocs); err != nil {
+		return nil, err
+	}
+	status.HasVectorIndex = len(status.Embeddings) > 0
+
+	if status.Oldest, err = s.documentAge("ASC"); err != nil {
+		return nil, err
+	}
+	if status.Newest, err = s.documentAge("DESC"); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ftsSize approximates the bytes held by the FTS shadow tables
+func (s *Store) ftsSize() (int64, error) {
+	var n int64
+	err := s.db.QueryRow(`
+		SELECT
+			(SELECT COALESCE(SUM(length(block)), 0) FROM documents_fts_data) +
+			(SELECT COALESCE(SUM(length(CAST(c0 AS BLOB)) + length(CAST(c1 AS BLOB)) + length(CAST(c2 AS BLOB))), 0) FROM documents_fts_content) +
+			(SELECT COALESCE(SUM(length(sz)), 0) FROM documents_fts_docsize)`).Scan(&n)
+	return n, err
+}
+
+func (s *Store) embeddingCoverage(totalDocs int) ([]ModelCoverage, error) {
+	rows, err := s.db.Query(`
+		SELECT e.model, MAX(e.dimensions), COUNT(*),
+			(SELECT COUNT(*) FROM documents d WHERE d.active = 1
+				AND EXISTS (SELECT 1 FROM embeddings x WHERE x.hash = d.hash AND x.model = e.model))
+		FROM embeddings e
+		GROUP BY e.model ORDER BY e.model`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	models := []ModelCoverage{}
+	for rows.Next() {
+		var m ModelCoverage
+		if err := rows.Scan(&m.Model, &m.Dimensions, &m.Chunks, &m.Docs); err != nil {
+			return nil, err
+		}
+		if totalDocs > 0 {
+			m.Coverage = float64(m.Docs) / float64(totalDocs)
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// documentAge returns the active document whose content changed first or last
+func (s *Store) documentAge(order string) (*DocumentAge, error) {
+	var d DocumentAge
+	err := s.db.QueryRow(`
+		SELECT collection, path, modified_at FROM documents WHERE active = 1
+		ORDER BY modified_at `+order+`, id `+order+` LIMIT 1`).Scan(&d.Collection, &d.Path, &d.ModifiedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// String formats the status for people
+func (st *Status) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gqmd Index Status:\n")
+	fmt.Fprintf(&b, "  Database: %s (%s, FTS %s, schema v%d)\n",
+		st.DBPath, FormatBytes(st.DBBytes), FormatBytes(st.FTSBytes), st.SchemaVersion)
+	fmt.Fprintf(&b, "  Total documents: %d (%s)\n", st.TotalDocs, FormatBytes(st.ContentBytes))
+	fmt.Fprintf(&b, "  Collections: %d\n", st.Collections)
+	fmt.Fprintf(&b, "  Has vector index: %v\n", st.HasVectorIndex)
+	if st.Model != "" {
+		fmt.Fprintf(&b, "  Needs embedding (%s): %d\n", st.Model, st.NeedsEmbedding)
+	} else {
+		fmt.Fprintf(&b, "  Needs embedding: %d\n", st.NeedsEmbedding)
+	}
+	if st.Oldest != nil {
+		fmt.Fprintf(&b, "  Oldest: %s/%s (%s)\n", st.Oldest.Collection, st.Oldest.Path, st.Oldest.ModifiedAt)
+		fmt.Fprintf(&b, "  Newest: %s/%s (%s)\n", st.Newest.Collection, st.Newest.Path, st.Newest.ModifiedAt)
+	}
+
+	if len(st.CollectionStats) > 0 {
+		b.WriteString("\nCollections:\n")
+		for _, c := range st.CollectionStats {
+			scan := "never scanned"
+			if c.LastScanAt != "" {
+				scan = fmt.Sprintf("scanned %s in %s", c.LastScanAt, time.Duration(c.LastScanMillis)*time.Millisecond)
+			}
+			fmt.Fprintf(&b, "  %s: %d docs, %s, %d need embedding, %s\n",
+				c.Name, c.Docs, FormatBytes(c.ContentBytes), c.NeedsEmbedding, scan)
+		}
+	}
+
+	if len(st.Embeddings) > 0 {
+		b.WriteString("\nEmbeddings:\n")
+		for _, m := range st.Embeddings {
+			fmt.Fprintf(&b, "  %s (%d dims): %d docs, %d chunks, %.0f%% coverage\n",
+				m.Model, m.Dimensions, m.Docs, m.Chunks, m.Coverage*100)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// FormatBytes prints a byte count with a binary unit, e.g. "1.5 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, exp := float64(n), 0
+	for f >= unit*unit || f <= -unit*unit {
+		f /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", f/unit, "KMGTPE"[exp])
+}
+
+// ParseBytes reads a byte count such as "512", "64K", "10MB" or "1.5GiB".
+// Units are binary, matching FormatBytes. Negative, non-finite and
+// overflowing counts are rejected.
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := strings.ToUpper(strings.TrimSpace(s[len(num):]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+
+	exp := 0
+	if unit != "" {
+		exp = strings.Index("KMGTPE", unit) + 1
+		if len(unit) != 1 || exp == 0 {
+			return 0, fmt.Errorf("invalid size %q", s)
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("invalid size %q: must not be negative", s)
+	}
+	n := f * math.Pow(1024, float64(exp))
+	if n >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return int64(n), nil
+}
+
+// ParseMaxFileSize reads a collection's file size limit: a byte count for
+// ParseBytes, or "none" for no limit
+func ParseMaxFileSize(s string) (int64, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "none") {
+		return -1, nil
+	}
+	return ParseBytes(s)
+}
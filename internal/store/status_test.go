@@ -0,0 +1,142 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetStatusWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	os.MkdirAll(docs, 0755)
+	os.WriteFile(filepath.Join(docs, "a.md"), []byte("# A\n\nalpha"), 0644)
+	os.WriteFile(filepath.Join(docs, "b.md"), []byte("# B\n\nbeta ünïcode"), 0644)
+
+	s, err := OpenPath(filepath.Join(dir, "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.AddCollection("docs", docs, "**/*.md")
+	s.AddCollection("empty", dir, "*.txt")
+	if _, err := s.ScanCollection("docs"); err != nil {
+		t.Fatal(err)
+	}
+	// Only a.md is embedded
+	a, _, _ := s.Get("docs", "a.md")
+	s.StoreEmbedding(a.Hash, 0, "m", Vector{1, 0})
+
+	status, err := s.GetStatusWithOptions(StatusOptions{Model: "m"})
+	if err != nil {
+		t.Fatalf("GetStatusWithOptions: %v", err)
+	}
+	if status.TotalDocs != 2 || status.Collections != 2 || !status.HasVectorIndex {
+		t.Errorf("status = %+v", status)
+	}
+	if status.SchemaVersion != SchemaVersion() {
+		t.Errorf("SchemaVersion = %d", status.SchemaVersion)
+	}
+	wantBytes := int64(len("# A\n\nalpha") + len("# B\n\nbeta ünïcode"))
+	if status.ContentBytes != wantBytes {
+		t.Errorf("ContentBytes = %d, want %d", status.ContentBytes, wantBytes)
+	}
+	if status.DBBytes == 0 || status.FTSBytes == 0 {
+		t.Errorf("DBBytes = %d, FTSBytes = %d", status.DBBytes, status.FTSBytes)
+	}
+	if status.NeedsEmbedding != 1 {
+		t.Errorf("NeedsEmbedding = %d, want 1", status.NeedsEmbedding)
+	}
+
+	col := status.CollectionStats[0]
+	if col.Name != "docs" || col.Docs != 2 || col.NeedsEmbedding != 1 || col.LastScanAt == "" {
+		t.Errorf("docs collection = %+v", col)
+	}
+	if empty := status.CollectionStats[1]; empty.Docs != 0 || empty.LastScanAt != "" {
+		t.Errorf("empty collection = %+v", empty)
+	}
+
+	if len(status.Embeddings) != 1 || status.Embeddings[0].Coverage != 0.5 || status.Embeddings[0].Dimensions != 2 {
+		t.Errorf("Embeddings = %+v", status.Embeddings)
+	}
+	if status.Oldest == nil || status.Newest == nil || status.Oldest.Path != "a.md" || status.Newest.Path != "b.md" {
+		t.Errorf("Oldest = %+v, Newest = %+v", status.Oldest, status.Newest)
+	}
+	if status.String() == "" {
+		t.Error("empty String()")
+	}
+}
+
+func TestModifiedAtKeptForUnchangedContent(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.IndexDocument("c", "a.md", "A", "same", "h1")
+	s.db.Exec(`UPDATE documents SET modified_at = '2000-01-01T00:00:00Z'`)
+
+	s.IndexDocument("c", "a.md", "A", "same", "h1")
+	doc, _, _ := s.Get("c", "a.md")
+	if doc.ModifiedAt != "2000-01-01T00:00:00Z" {
+		t.Errorf("unchanged content bumped ModifiedAt to %s", doc.ModifiedAt)
+	}
+
+	s.IndexDocument("c", "a.md", "A", "edited", "h2")
+	doc, _, _ = s.Get("c", "a.md")
+	if _, err := time.Parse(time.RFC3339, doc.ModifiedAt); err != nil || doc.ModifiedAt == "2000-01-01T00:00:00Z" {
+		t.Errorf("edited content ModifiedAt = %s", doc.ModifiedAt)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"512", 512},
+		{"100B", 100},
+		{"64K", 64 << 10},
+		{"10MB", 10 << 20},
+		{"10 MiB", 10 << 20},
+		{"1.5g", 3 << 29},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "MB", "10XB", "ten", "-5", "-1K", "NaN", "Inf", "-Inf", "+Inf", "1e30", "8E"} {
+		if _, err := ParseBytes(s); err == nil {
+			t.Errorf("ParseBytes(%q) succeeded, want error", s)
+		}
+	}
+	if n, err := ParseMaxFileSize("none"); err != nil || n != -1 {
+		t.Errorf("ParseMaxFileSize(none) = %d, %v, want -1", n, err)
+	}
+	if _, err := ParseMaxFileSize("-1"); err == nil {
+		t.Error("ParseMaxFileSize(-1) succeeded, want error")
+	}
+}
@@ -0,0 +1,53 @@
+-- Schema and data of an index created before schema versioning
+CREATE TABLE collections (
+	id INTEGER PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	path TEXT NOT NULL,
+	pattern TEXT DEFAULT '**/*.md',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE content (
+	hash TEXT PRIMARY KEY,
+	doc TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+CREATE TABLE documents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	collection TEXT NOT NULL,
+	path TEXT NOT NULL,
+	title TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	modified_at TEXT NOT NULL,
+	active INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (hash) REFERENCES content(hash) ON DELETE CASCADE,
+	UNIQUE(collection, path)
+);
+CREATE INDEX idx_documents_collection ON documents(collection, active);
+CREATE INDEX idx_documents_hash ON documents(hash);
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+	filepath, title, body,
+	tokenize='porter unicode61'
+);
+CREATE TABLE embeddings (
+	hash TEXT NOT NULL,
+	chunk_idx INTEGER NOT NULL DEFAULT 0,
+	model TEXT NOT NULL,
+	dimensions INTEGER NOT NULL,
+	vector BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (hash, chunk_idx)
+);
+
+INSERT INTO collections (name, path, pattern) VALUES ('notes', '/tmp/notes', '**/*.md');
+INSERT INTO content (hash, doc, created_at) VALUES
+	('3f2a9c0e5b7d1f4a6c8e0b2d4f6a8c0e2b4d6f8a0c2e4b6d8f0a2c4e6b8d0f2a',
+	 '# Golang Guide' || char(10) || char(10) || 'Goroutines and channels.', '2024-01-01T00:00:00Z');
+INSERT INTO documents (collection, path, title, hash, created_at, modified_at) VALUES
+	('notes', 'go.md', 'Golang Guide', '3f2a9c0e5b7d1f4a6c8e0b2d4f6a8c0e2b4d6f8a0c2e4b6d8f0a2c4e6b8d0f2a',
+	 '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z');
+INSERT INTO documents_fts (rowid, filepath, title, body) VALUES
+	(1, 'notes/go.md', 'Golang Guide', '# Golang Guide' || char(10) || char(10) || 'Goroutines and channels.');
+INSERT INTO embeddings (hash, chunk_idx, model, dimensions, vector, created_at) VALUES
+	('3f2a9c0e5b7d1f4a6c8e0b2d4f6a8c0e2b4d6f8a0c2e4b6d8f0a2c4e6b8d0f2a', 0, 'nomic-embed-text', 2,
+	 X'0000803F00000000', '2024-01-01T00:00:00Z');
@@ -1,8 +1,16 @@
 package store
 
 import (
+	"database/sql"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"math"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/NOTAschool/gqmd/internal/extract"
 )
 
 // Vector represents an embedding vector
@@ -10,11 +18,18 @@ type Vector []float32
 
 // VectorResult holds vector search result
 type VectorResult struct {
-	Collection string
-	Path       string
-	Title      string
-	Score      float64
-	ChunkIdx   int
+	Collection  string
+	Path        string
+	Title       string
+	DocID       string
+	Context     string
+	Score       float64 // cosine similarity
+	ChunkIdx    int
+	Text        string // matching chunk text, trimmed to SearchOptions.SnippetLength
+	FromLine    int
+	ToLine      int
+	Breadcrumb  string
+	Explanation *Explanation // set when SearchOptions.Explain is true
 }
 
 // vectorToBlob converts float32 slice to bytes
@@ -54,36 +69,74 @@ func cosineSimilarity(a, b Vector) float64 {
 
 // StoreEmbedding stores a vector embedding for a document
 func (s *Store) StoreEmbedding(hash string, chunkIdx int, model string, vec Vector) error {
-	blob := vectorToBlob(vec)
-	now := nowISO()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := storeEmbeddingTx(tx, hash, chunkIdx, model, vec, nowISO()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	_, err := s.db.Exec(`
+func storeEmbeddingTx(tx *sql.Tx, hash string, chunkIdx int, model string, vec Vector, now string) error {
+	_, err := tx.Exec(`
 		INSERT OR REPLACE INTO embeddings (hash, chunk_idx, model, dimensions, vector, created_at)
 		VALUES (?, ?, ?, ?, ?, ?)`,
-		hash, chunkIdx, model, len(vec), blob, now,
+		hash, chunkIdx, model, len(vec), vectorToBlob(vec), now,
 	)
 	return err
 }
 
 // VectorSearch performs vector similarity search
 func (s *Store) VectorSearch(queryVec Vector, limit int) ([]VectorResult, error) {
+	results, _, err := s.VectorSearchWithOptions(queryVec, SearchOptions{Limit: limit})
+	return results, err
+}
+
+// VectorSearchWithOptions performs vector similarity search with paging, a
+// score threshold and optional explanations. Results are ordered by score,
+// then document id and chunk, so pages are stable.
+func (s *Store) VectorSearchWithOptions(queryVec Vector, opts SearchOptions) ([]VectorResult, *Page, error) {
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 10
 	}
+	snippetLength := opts.SnippetLength
+	if snippetLength == 0 {
+		snippetLength = DefaultSnippetLength
+	}
+	query := string(vectorToBlob(queryVec))
+	offset, err := opts.resolvePage("vec", query)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Get all embeddings
+	contexts, err := s.loadContexts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get all embeddings of the query's model
 	rows, err := s.db.Query(`
-		SELECT e.hash, e.chunk_idx, e.vector, d.collection, d.path, d.title
+		SELECT e.hash, e.chunk_idx, e.vector, d.id, d.collection, d.path, d.title,
+			COALESCE(c.text, ''), COALESCE(c.from_line, 0), COALESCE(c.to_line, 0), COALESCE(c.breadcrumb, '')
 		FROM embeddings e
-		JOIN documents d ON d.hash = e.hash AND d.active = 1`)
+		JOIN documents d ON d.hash = e.hash AND d.active = 1
+		LEFT JOIN chunks c ON c.hash = e.hash AND c.chunk_idx = e.chunk_idx
+		WHERE ? = '' OR e.model = ?`,
+		opts.Model, opts.Model,
+	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	type scored struct {
 		result VectorResult
-		score  float64
+		docID  int64
+		hash   string
 	}
 	var all []scored
 
@@ -91,41 +144,235 @@ func (s *Store) VectorSearch(queryVec Vector, limit int) ([]VectorResult, error)
 		var hash string
 		var chunkIdx int
 		var blob []byte
+		var docID int64
 		var col, path, title string
+		var text, crumb string
+		var fromLine, toLine int
 
-		if err := rows.Scan(&hash, &chunkIdx, &blob, &col, &path, &title); err != nil {
+		if err := rows.Scan(&hash, &chunkIdx, &blob, &docID, &col, &path, &title,
+			&text, &fromLine, &toLine, &crumb); err != nil {
 			continue
 		}
 
 		vec := blobToVector(blob)
 		score := cosineSimilarity(queryVec, vec)
+		if score < opts.MinScore {
+			continue
+		}
 
 		all = append(all, scored{
 			result: VectorResult{
 				Collection: col,
 				Path:       path,
 				Title:      title,
+				Context:    contexts.lookup(col, path),
 				Score:      score,
 				ChunkIdx:   chunkIdx,
+				Text:       trimText(text, snippetLength),
+				FromLine:   fromLine,
+				ToLine:     toLine,
+				Breadcrumb: crumb,
 			},
-			score: score,
+			docID: docID,
+			hash:  hash,
 		})
 	}
 
-	// Sort by score descending
-	for i := 0; i < len(all)-1; i++ {
-		for j := i + 1; j < len(all); j++ {
-			if all[j].score > all[i].score {
-				all[i], all[j] = all[j], all[i]
+	// Sort by score descending, then document id and chunk
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.result.Score != b.result.Score {
+			return a.result.Score > b.result.Score
+		}
+		if a.docID != b.docID {
+			return a.docID < b.docID
+		}
+		return a.result.ChunkIdx < b.result.ChunkIdx
+	})
+
+	// Keep only the best chunk of each document
+	if opts.Collapse {
+		seen := make(map[int64]bool)
+		collapsed := all[:0]
+		for _, sc := range all {
+			if !seen[sc.docID] {
+				seen[sc.docID] = true
+				collapsed = append(collapsed, sc)
 			}
 		}
+		all = collapsed
 	}
 
-	// Return top results
+	// Return the requested page
 	results := make([]VectorResult, 0, limit)
-	for i := 0; i < len(all) && i < limit; i++ {
-		results = append(results, all[i].result)
+	for i := offset; i < len(all) && len(results) < limit; i++ {
+		r := all[i].result
+		if r.DocID, err = s.docID(all[i].hash); err != nil {
+			return nil, nil, err
+		}
+		if opts.Explain {
+			r.Explanation = &Explanation{Rank: i + 1, Similarity: r.Score}
+		}
+		results = append(results, r)
 	}
+	hasMore := offset+len(results) < len(all)
+
+	return results, opts.newPage("vec", query, offset, len(results), hasMore), nil
+}
+
+// EmbedFunc returns the embedding vector for a text
+type EmbedFunc func(text string) ([]float32, error)
 
-	return results, nil
+// EmbedOptions configures EmbedDocuments
+type EmbedOptions struct {
+	Collection string // empty for all collections
+	Progress   ProgressFunc
+}
+
+// EmbedResult holds embedding statistics
+type EmbedResult struct {
+	Embedded int
+	Errors   int
+}
+
+// PendingEmbeddings returns the content hashes of active documents that
+// have no embedding for model yet
+func (s *Store) PendingEmbeddings(model, collection string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT d.hash FROM documents d
+		WHERE d.active = 1 AND (? = '' OR d.collection = ?)
+			AND NOT EXISTS (SELECT 1 FROM embeddings e WHERE e.hash = d.hash AND e.model = ?)
+		ORDER BY d.hash`,
+		collection, collection, model,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// maxEmbedFailures is how many documents in a row may fail before
+// EmbedDocuments gives up, as a misconfigured model fails every request
+const maxEmbedFailures = 5
+
+// EmbedDocuments embeds the content of every document that has no
+// embedding for model yet, reporting progress per document. Documents
+// that fail are counted; a network error, or maxEmbedFailures documents
+// failing in a row, stops the run, as every later request would most
+// likely fail the same way.
+func (s *Store) EmbedDocuments(model string, embed EmbedFunc, opts EmbedOptions) (*EmbedResult, error) {
+	hashes, err := s.PendingEmbeddings(model, opts.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EmbedResult{}
+	failures := 0
+	for i, hash := range hashes {
+		err := s.embedContent(hash, model, embed)
+		opts.Progress.report(i+1, len(hashes), DocID(hash))
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) {
+				return result, err
+			}
+			result.Errors++
+			if failures++; failures == maxEmbedFailures {
+				return result, fmt.Errorf("%d documents in a row failed to embed: %w", failures, err)
+			}
+			continue
+		}
+		failures = 0
+		result.Embedded++
+	}
+
+	return result, nil
+}
+
+func (s *Store) embedContent(hash, model string, embed EmbedFunc) error {
+	var content string
+	if err := s.db.QueryRow(`SELECT doc FROM content WHERE hash = ?`, hash).Scan(&content); err != nil {
+		return err
+	}
+
+	// Embed every chunk before writing any, so a failure leaves the
+	// document pending rather than half embedded
+	chunks := ChunkContent(content, DefaultChunkBytes)
+	if len(chunks) == 0 {
+		// Content without text, such as a notebook of code cells only, is
+		// embedded by its title or path, or it would stay pending forever
+		var title, path string
+		if err := s.db.QueryRow(`SELECT title, path FROM documents WHERE hash = ? ORDER BY id LIMIT 1`,
+			hash).Scan(&title, &path); err != nil {
+			return err
+		}
+		if strings.TrimSpace(title) == "" {
+			title = path
+		}
+		chunks = []Chunk{{Text: title, FromLine: 1, ToLine: max(len(extract.SplitLines(content)), 1)}}
+	}
+	vecs := make([]Vector, len(chunks))
+	for i, c := range chunks {
+		text := c.Text
+		if c.Breadcrumb != "" {
+			text = c.Breadcrumb + "\n\n" + text
+		}
+		vec, err := embed(text)
+		if err != nil {
+			return err
+		}
+		vecs[i] = Vector(vec)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM embeddings WHERE hash = ? AND model = ?`, hash, model); err != nil {
+		return err
+	}
+	now := nowISO()
+	for i, c := range chunks {
+		if err := storeChunkTx(tx, hash, c); err != nil {
+			return err
+		}
+		if err := storeEmbeddingTx(tx, hash, c.Index, model, vecs[i], now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// StoreChunk stores the text and position of an embedded chunk
+func (s *Store) StoreChunk(hash string, c Chunk) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := storeChunkTx(tx, hash, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func storeChunkTx(tx *sql.Tx, hash string, c Chunk) error {
+	_, err := tx.Exec(`
+		INSERT OR REPLACE INTO chunks (hash, chunk_idx, from_line, to_line, breadcrumb, text)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		hash, c.Index, c.FromLine, c.ToLine, c.Breadcrumb, c.Text,
+	)
+	return err
 }
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keywordEmbed is a fake embedder with one dimension per keyword
+func keywordEmbed(text string) ([]float32, error) {
+	text = strings.ToLower(text)
+	vec := make([]float32, 3)
+	for i, kw := range []string{"alpha", "beta", "gamma"} {
+		vec[i] = float32(strings.Count(text, kw)) + 0.01
+	}
+	return vec, nil
+}
+
+func TestVectorSearchChunks(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	doc := "# Notes\nintro\n## Alpha\nalpha alpha alpha\n## Alpha again\nalpha beta\n## Gamma\ngamma\n"
+	s.IndexDocument("docs", "notes.md", "Notes", doc, "aaa111")
+	if _, err := s.EmbedDocuments("fake", keywordEmbed, EmbedOptions{}); err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+
+	query, _ := keywordEmbed("alpha")
+	results, _, err := s.VectorSearchWithOptions(Vector(query), SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("VectorSearch failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("VectorSearch = %d results, want 4 chunks", len(results))
+	}
+	top := results[0]
+	if top.Breadcrumb != "Notes > Alpha" || top.FromLine != 3 || top.ToLine != 4 {
+		t.Errorf("top hit = %+v", top)
+	}
+	if top.Text != "## Alpha alpha alpha alpha" {
+		t.Errorf("top hit text = %q", top.Text)
+	}
+
+	results, _, _ = s.VectorSearchWithOptions(Vector(query), SearchOptions{Collapse: true, SnippetLength: 8})
+	if len(results) != 1 {
+		t.Fatalf("collapsed results = %d, want 1", len(results))
+	}
+	if results[0].Text != "## Alpha..." {
+		t.Errorf("trimmed text = %q", results[0].Text)
+	}
+}
+
+func TestVectorSearchPaging(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	// Four chunks in each of three documents
+	for i := 0; i < 3; i++ {
+		doc := fmt.Sprintf("# Doc %d\n## One\nalpha\n## Two\nalpha beta\n## Three\ngamma\n", i)
+		s.IndexDocument("docs", fmt.Sprintf("doc%d.md", i), "Doc", doc, fmt.Sprintf("aaa%03d", i))
+	}
+	if _, err := s.EmbedDocuments("fake", keywordEmbed, EmbedOptions{}); err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+	query, _ := keywordEmbed("alpha")
+
+	// collect pages through every result with the cursor
+	collect := func(opts SearchOptions) []string {
+		var hits []string
+		for pages := 0; ; pages++ {
+			results, page, err := s.VectorSearchWithOptions(Vector(query), opts)
+			if err != nil {
+				t.Fatalf("VectorSearch failed: %v", err)
+			}
+			for _, r := range results {
+				hits = append(hits, fmt.Sprintf("%s#%d", r.Path, r.ChunkIdx))
+			}
+			if !page.HasMore {
+				return hits
+			}
+			if pages > 10 {
+				t.Fatal("paging did not terminate")
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+
+	all := collect(SearchOptions{Limit: 2})
+	if len(all) != 12 {
+		t.Errorf("paged chunks = %v, want 12", all)
+	}
+	seen := make(map[string]bool)
+	for _, h := range all {
+		if seen[h] {
+			t.Errorf("chunk %s returned twice", h)
+		}
+		seen[h] = true
+	}
+
+	collapsed := collect(SearchOptions{Limit: 2, Collapse: true})
+	want := []string{"doc0.md#1", "doc1.md#1", "doc2.md#1"}
+	if strings.Join(collapsed, ",") != strings.Join(want, ",") {
+		t.Errorf("collapsed pages = %v, want %v", collapsed, want)
+	}
+
+	// A cursor from collapsed results is not valid for chunk results
+	_, page, _ := s.VectorSearchWithOptions(Vector(query), SearchOptions{Limit: 2, Collapse: true})
+	if _, _, err := s.VectorSearchWithOptions(Vector(query), SearchOptions{Limit: 2, Cursor: page.NextCursor}); err == nil {
+		t.Error("expected error reusing a collapsed cursor without collapse")
+	}
+}
+
+func TestEmbedDocumentsAtomic(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	doc := "# Notes\n## Alpha\nalpha\n## Beta\nbeta\n## Gamma\ngamma\n"
+	s.IndexDocument("docs", "notes.md", "Notes", doc, "aaa111")
+
+	// Fail on the second chunk: nothing is written and the document stays pending
+	calls := 0
+	flaky := func(text string) ([]float32, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("model overloaded")
+		}
+		return keywordEmbed(text)
+	}
+	result, err := s.EmbedDocuments("fake", flaky, EmbedOptions{})
+	if err != nil || result.Errors != 1 {
+		t.Fatalf("EmbedDocuments = %+v, %v, want 1 error", result, err)
+	}
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&n)
+	if n != 0 {
+		t.Errorf("embeddings after failure = %d, want 0", n)
+	}
+
+	result, err = s.EmbedDocuments("fake", flaky, EmbedOptions{})
+	if err != nil || result.Embedded != 1 {
+		t.Fatalf("retry = %+v, %v, want 1 embedded", result, err)
+	}
+	s.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&n)
+	if want := len(ChunkContent(doc, DefaultChunkBytes)); n != want {
+		t.Errorf("embeddings after retry = %d, want %d", n, want)
+	}
+}
+
+func TestEmbedEmptyDocuments(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.AddCollection("docs", t.TempDir(), "")
+	s.IndexDocument("docs", "empty.ipynb", "Analysis", "", "aaa111")
+	s.IndexDocument("docs", "blank.go", "", "\n  \n", "bbb222")
+
+	var texts []string
+	record := func(text string) ([]float32, error) {
+		texts = append(texts, text)
+		return []float32{1, 0}, nil
+	}
+	result, err := s.EmbedDocuments("fake", record, EmbedOptions{})
+	if err != nil || result.Embedded != 2 {
+		t.Fatalf("EmbedDocuments = %+v, %v, want 2 embedded", result, err)
+	}
+	if strings.Join(texts, ",") != "Analysis,blank.go" {
+		t.Errorf("embedded texts = %q, want the title, then the path", texts)
+	}
+
+	pending, err := s.PendingEmbeddings("fake", "")
+	if err != nil || len(pending) != 0 {
+		t.Errorf("PendingEmbeddings = %v, %v, want none", pending, err)
+	}
+	status, err := s.GetStatusWithOptions(StatusOptions{Model: "fake"})
+	if err != nil || status.NeedsEmbedding != 0 {
+		t.Errorf("NeedsEmbedding = %+v, %v, want 0", status, err)
+	}
+	if result, err := s.EmbedDocuments("fake", record, EmbedOptions{}); err != nil || result.Embedded != 0 {
+		t.Errorf("second EmbedDocuments = %+v, %v, want nothing embedded", result, err)
+	}
+}
+
+func TestVectorSearchModels(t *testing.T) {
+	s, err := OpenPath(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer s.Close()
+
+	s.IndexDocument("docs", "notes.md", "Notes", "alpha\n", "aaa111")
+	reversed := func(text string) ([]float32, error) {
+		vec, _ := keywordEmbed(text)
+		return []float32{vec[2], vec[1], vec[0]}, nil
+	}
+	if _, err := s.EmbedDocuments("fake", keywordEmbed, EmbedOptions{}); err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+	if _, err := s.EmbedDocuments("reversed", reversed, EmbedOptions{}); err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+
+	// A second model adds embeddings rather than replacing the first
+	var n int
+	s.db.QueryRow(`SELECT COUNT(DISTINCT model) FROM embeddings WHERE hash = 'aaa111'`).Scan(&n)
+	if n != 2 {
+		t.Errorf("models embedded = %d, want 2", n)
+	}
+
+	query, _ := keywordEmbed("alpha")
+	for model, want := range map[string]int{"fake": 1, "reversed": 1, "": 2} {
+		results, _, err := s.VectorSearchWithOptions(Vector(query), SearchOptions{Model: model})
+		if err != nil {
+			t.Fatalf("VectorSearch(%q) failed: %v", model, err)
+		}
+		if len(results) != want {
+			t.Errorf("VectorSearch(%q) = %d results, want %d", model, len(results), want)
+		}
+	}
+	results, _, _ := s.VectorSearchWithOptions(Vector(query), SearchOptions{Model: "fake"})
+	if len(results) == 1 && results[0].Score < 0.99 {
+		t.Errorf("score against own model = %f", results[0].Score)
+	}
+}
@@ -0,0 +1,297 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the watcher waits for a burst of edits to settle
+const DefaultDebounce = 500 * time.Millisecond
+
+// DefaultReload is how often the watcher rereads the collection list, so
+// collections added or removed while it runs are picked up
+const DefaultReload = 5 * time.Second
+
+// Options configures a Watcher
+type Options struct {
+	Debounce time.Duration
+	Reload   time.Duration
+	// Embed, if set, embeds documents whose content changed after each batch
+	Embed store.EmbedFunc
+	Model string
+	Logf  func(format string, args ...any)
+}
+
+// Watcher keeps the index in sync with collection directories using
+// filesystem notifications
+type Watcher struct {
+	db      *store.Store
+	opts    Options
+	fsw     *fsnotify.Watcher
+	cols    []store.Collection
+	pending map[string]bool
+}
+
+// New creates a watcher for all registered collections
+func New(db *store.Store, opts Options) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Reload <= 0 {
+		opts.Reload = DefaultReload
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...any) {}
+	}
+
+	cols, err := db.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+
+	w := &Watcher{db: db, opts: opts, fsw: fsw, cols: cols, pending: make(map[string]bool)}
+	for _, c := range cols {
+		if err := w.addTree(c.Path); err != nil {
+			fsw.Close()
+			return nil, fmt.Errorf("watch %s: %w", c.Name, err)
+		}
+	}
+	return w, nil
+}
+
+// Run applies filesystem changes to the index until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	defer w.fsw.Close()
+
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+	reload := time.NewTicker(w.opts.Reload)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			// Watch directories created or moved into a collection
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := w.addTree(ev.Name); err != nil {
+						w.opts.Logf("watch %s: %v", ev.Name, err)
+					}
+				}
+			}
+			w.pending[ev.Name] = true
+			timer.Reset(w.opts.Debounce)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.opts.Logf("watch error: %v", err)
+
+		case <-timer.C:
+			w.flush()
+
+		case <-reload.C:
+			if err := w.reload(); err != nil {
+				w.opts.Logf("reload collections: %v", err)
+			}
+		}
+	}
+}
+
+// reload rereads the collection list, watching the roots of new
+// collections and dropping the watches of removed ones. Only later
+// changes are indexed; files a new collection already has are not, until
+// it is scanned.
+func (w *Watcher) reload() error {
+	cols, err := w.db.ListCollections()
+	if err != nil {
+		return err
+	}
+
+	old := make(map[string]bool, len(w.cols))
+	for _, c := range w.cols {
+		old[c.Path] = true
+	}
+	roots := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		roots[c.Path] = true
+		if old[c.Path] {
+			continue
+		}
+		if err := w.addTree(c.Path); err != nil {
+			w.opts.Logf("watch %s: %v", c.Name, err)
+			continue
+		}
+		w.opts.Logf("watching collection %s", c.Name)
+	}
+
+	// Drop watches no remaining root covers, keeping nested collections
+	for _, path := range w.fsw.WatchList() {
+		covered := false
+		for root := range roots {
+			if within(path, root) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			w.fsw.Remove(path)
+		}
+	}
+
+	// Collection settings such as the pattern may have changed too
+	w.cols = cols
+	return nil
+}
+
+// within reports whether path is root or below it
+func within(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// addTree watches a directory and all directories below it
+func (w *Watcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			return w.fsw.Add(path)
+		}
+		return nil
+	})
+}
+
+// flush applies the pending changes to the index
+func (w *Watcher) flush() {
+	changed := 0
+	for path := range w.pending {
+		col, rel, ok := w.locate(path)
+		if !ok {
+			continue
+		}
+		n, err := w.apply(col, path, rel)
+		if err != nil {
+			w.opts.Logf("%s/%s: %v", col.Name, rel, err)
+		}
+		changed += n
+	}
+	w.pending = make(map[string]bool)
+
+	if changed == 0 || w.opts.Embed == nil {
+		return
+	}
+	result, err := w.db.EmbedDocuments(w.opts.Model, w.opts.Embed, store.EmbedOptions{})
+	if err != nil {
+		w.opts.Logf("embed: %v", err)
+		return
+	}
+	if result.Embedded > 0 {
+		w.opts.Logf("embedded %d documents", result.Embedded)
+	}
+}
+
+// apply reindexes or removes whatever is now at path
+func (w *Watcher) apply(col *store.Collection, path, rel string) (int, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// A deleted or moved-away file or directory
+		removed, err := w.db.RemoveDocumentsUnder(col.Name, rel)
+		if err != nil {
+			return 0, err
+		}
+		if err := w.db.RemoveDocument(col.Name, rel); err == nil {
+			removed++
+		}
+		if removed > 0 {
+			w.opts.Logf("removed %d documents under %s/%s", removed, col.Name, rel)
+		}
+		return removed, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !info.IsDir() {
+		if !col.Matches(rel) {
+			return 0, nil
+		}
+		var skip *store.SkipError
+		if err := w.db.IndexFile(col, rel); errors.As(err, &skip) {
+			w.opts.Logf("skipped %s/%s: %s", col.Name, rel, skip.Reason)
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		w.opts.Logf("indexed %s/%s", col.Name, rel)
+		return 1, nil
+	}
+
+	// A directory created or moved in: index everything below it
+	indexed := 0
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		r, err := filepath.Rel(col.Path, p)
+		if err != nil || !col.Matches(r) {
+			return nil
+		}
+		if err := w.db.IndexFile(col, r); err != nil {
+			w.opts.Logf("%s/%s: %v", col.Name, r, err)
+			return nil
+		}
+		indexed++
+		return nil
+	})
+	if indexed > 0 {
+		w.opts.Logf("indexed %d documents under %s/%s", indexed, col.Name, rel)
+	}
+	return indexed, err
+}
+
+// locate finds the collection containing path, preferring the deepest root
+func (w *Watcher) locate(path string) (*store.Collection, string, bool) {
+	var best *store.Collection
+	for i := range w.cols {
+		c := &w.cols[i]
+		if !within(path, c.Path) {
+			continue
+		}
+		if best == nil || len(c.Path) > len(best.Path) {
+			best = c
+		}
+	}
+	if best == nil || path == best.Path {
+		return nil, "", false
+	}
+	rel, err := filepath.Rel(best.Path, path)
+	if err != nil {
+		return nil, "", false
+	}
+	return best, rel, true
+}
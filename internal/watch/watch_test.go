@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NOTAschool/gqmd/internal/store"
+)
+
+// waitFor polls until cond holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := store.OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer db.Close()
+
+	root := filepath.Join(tmpDir, "notes")
+	os.MkdirAll(filepath.Join(root, "old"), 0755)
+	os.WriteFile(filepath.Join(root, "old", "b.md"), []byte("# B\nbeta"), 0644)
+	db.AddCollection("notes", root, "")
+	if _, err := db.ScanCollection("notes"); err != nil {
+		t.Fatalf("ScanCollection failed: %v", err)
+	}
+
+	w, err := New(db, Options{Debounce: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	exists := func(path string) func() bool {
+		return func() bool {
+			_, _, err := db.Get("notes", path)
+			return err == nil
+		}
+	}
+	found := func(query string, n int) func() bool {
+		return func() bool {
+			results, err := db.Search(query, 10)
+			return err == nil && len(results) == n
+		}
+	}
+
+	// New file
+	os.WriteFile(filepath.Join(root, "a.md"), []byte("# A\nalpha"), 0644)
+	waitFor(t, "new file", exists("a.md"))
+
+	// Edit
+	os.WriteFile(filepath.Join(root, "a.md"), []byte("# A\ngamma"), 0644)
+	waitFor(t, "edit", found("gamma", 1))
+	waitFor(t, "old content gone", found("alpha", 0))
+
+	// Directory move
+	os.Rename(filepath.Join(root, "old"), filepath.Join(root, "new"))
+	waitFor(t, "moved file", exists("new/b.md"))
+	waitFor(t, "old path removed", func() bool { return !exists("old/b.md")() })
+
+	// Delete
+	os.Remove(filepath.Join(root, "a.md"))
+	waitFor(t, "deleted file", found("gamma", 0))
+}
+
+func TestWatcherReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := store.OpenPath(filepath.Join(tmpDir, "test.sqlite"))
+	if err != nil {
+		t.Fatalf("OpenPath failed: %v", err)
+	}
+	defer db.Close()
+
+	w, err := New(db, Options{Debounce: 50 * time.Millisecond, Reload: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// A collection added while the watcher runs
+	root := filepath.Join(tmpDir, "late")
+	os.MkdirAll(root, 0755)
+	db.AddCollection("late", root, "")
+	waitFor(t, "new root watched", func() bool { return len(w.fsw.WatchList()) == 1 })
+
+	os.WriteFile(filepath.Join(root, "a.md"), []byte("# A\nalpha"), 0644)
+	waitFor(t, "file in new collection", func() bool {
+		_, _, err := db.Get("late", "a.md")
+		return err == nil
+	})
+
+	// A removed collection is no longer watched
+	db.RemoveCollection("late")
+	waitFor(t, "removed root unwatched", func() bool { return len(w.fsw.WatchList()) == 0 })
+}